@@ -23,11 +23,14 @@ func NewAPI(c *bot.Client, mux *http.ServeMux) *API {
 	mux.HandleFunc("/metrics", api.handleMetrics)
 	mux.HandleFunc("/api/v1/users", api.handleUsers)
 	mux.HandleFunc("/api/v1/command", api.handleCommand)
+	mux.HandleFunc("/api/v1/commands", api.handleCommands)
 	mux.HandleFunc("/api/v1/clips", api.handleClips)
 	mux.HandleFunc("/api/v1/hold", api.handleHold)
 	mux.HandleFunc("/api/v1/stickers", api.handleStickers)
 	mux.HandleFunc("/api/v1/aliases", api.handleAliases)
 	mux.HandleFunc("/api/v1/volume", api.handleVolume)
+	mux.HandleFunc("/api/v1/transcript", api.handleTranscript)
+	mux.HandleFunc("/api/v1/audit", api.handleAudit)
 
 	return api
 }
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/silkeh/mumble_bot/bot"
+)
+
+const UnauthorizedError = "invalid or missing API token"
+
+// anonymousSender is the sender for API requests without a token.
+var anonymousSender = &bot.Sender{Name: "api"}
+
+// sender returns the sender identified by the bearer token in a request.
+// Requests without a token are allowed when no tokens are configured.
+func (api *API) sender(req *http.Request) (*bot.Sender, error) {
+	var tokens map[string]string
+	if api.client.Config.API != nil {
+		tokens = api.client.Config.API.Tokens
+	}
+
+	auth := req.Header.Get("Authorization")
+	if auth == "" && len(tokens) == 0 {
+		return anonymousSender, nil
+	}
+
+	secret := strings.TrimPrefix(auth, "Bearer ")
+	for name, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1 {
+			return bot.APISender(name), nil
+		}
+	}
+
+	return nil, errors.New(UnauthorizedError)
+}
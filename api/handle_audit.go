@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/silkeh/mumble_bot/bot"
+)
+
+type Audit struct {
+	Entries []*bot.AuditEntry
+}
+
+func (api *API) handleAudit(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		WriteMethodNotAllowed(w)
+		return
+	}
+
+	sender, err := api.sender(req)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err := api.client.CheckAuditPermission(api.client.NewContext(bot.SourceAPI, sender)); err != nil {
+		WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	query := req.URL.Query()
+	var since, until time.Time
+	for k, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		if s := query.Get(k); s != "" {
+			var err error
+			*t, err = time.Parse(time.RFC3339, s)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	entries, err := api.client.AuditLog(since, until, query.Get("user"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(&Audit{Entries: entries})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,37 +2,66 @@ package api
 
 import (
 	"encoding/json"
-	"layeh.com/gumble/gumble"
 	"net/http"
-	"strings"
+
+	"github.com/silkeh/mumble_bot/bot"
 )
 
 type Command struct {
 	Command string
 }
 
+type CommandResult struct {
+	Status      bot.Status
+	Text        string
+	HTML        string
+	Attachments []bot.Attachment
+}
+
+// statusCodes maps the status of command results to HTTP status codes.
+var statusCodes = map[bot.Status]int{
+	bot.StatusOK:          http.StatusOK,
+	bot.StatusError:       http.StatusUnprocessableEntity,
+	bot.StatusInvalid:     http.StatusBadRequest,
+	bot.StatusDenied:      http.StatusForbidden,
+	bot.StatusRateLimited: http.StatusTooManyRequests,
+	bot.StatusUnknown:     http.StatusNotFound,
+}
+
 func (api *API) handleCommand(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		WriteMethodNotAllowed(w)
 		return
 	}
 
+	sender, err := api.sender(req)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
 	var cmd Command
-	err := json.NewDecoder(req.Body).Decode(&cmd)
+	err = json.NewDecoder(req.Body).Decode(&cmd)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	msg := api.client.HandleCommand(cmd.Command)
-	if !strings.HasPrefix(msg, "Unknown command") &&
-		!strings.HasPrefix(msg, "Error") {
-		api.client.Mumble.Send(&gumble.TextMessage{
-			Sender:   api.client.Mumble.Self,
-			Channels: []*gumble.Channel{api.client.Mumble.Channels.Find()},
-			Message:  msg,
-		})
+	ctx := api.client.NewContext(bot.SourceAPI, sender)
+	result := api.client.HandleCommand(ctx, cmd.Command)
+	if result.OK() {
+		api.client.SendReply(ctx, result)
 	}
 
-	w.Write([]byte(msg))
+	code, ok := statusCodes[result.Status]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&CommandResult{
+		Status:      result.Status,
+		Text:        result.PlainText(),
+		HTML:        result.HTML,
+		Attachments: result.Attachments,
+	})
 }
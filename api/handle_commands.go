@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type CommandInfo struct {
+	Usage    string
+	Summary  string
+	Args     []Argument
+	Examples []string
+}
+
+type Argument struct {
+	Name        string
+	Description string
+	Optional    bool
+	Repeated    bool
+}
+
+type Commands struct {
+	Commands map[string]*CommandInfo
+	Aliases  map[string]string
+}
+
+func (api *API) handleCommands(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		WriteMethodNotAllowed(w)
+		return
+	}
+
+	commands := api.client.Commands()
+	resp := &Commands{
+		Commands: make(map[string]*CommandInfo, len(commands)),
+		Aliases:  make(map[string]string),
+	}
+	for name, cmd := range commands {
+		info := &CommandInfo{
+			Usage:    cmd.Usage(name),
+			Summary:  cmd.Summary,
+			Args:     make([]Argument, len(cmd.Args)),
+			Examples: cmd.Examples,
+		}
+		for i, a := range cmd.Args {
+			info.Args[i] = Argument(a)
+		}
+		resp.Commands[name] = info
+	}
+	if api.client.Config.Mumble != nil {
+		for name, alias := range api.client.Config.Mumble.Alias {
+			if _, ok := commands[name]; !ok {
+				resp.Aliases[name] = alias
+			}
+		}
+	}
+
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -16,6 +16,9 @@ func (api *API) handleMetrics(w http.ResponseWriter, req *http.Request) {
 	}
 
 	fmt.Fprintf(w, "mumble_connected_users %v\n", len(api.client.Mumble.Users))
+	for cmd, n := range api.client.RateLimited() {
+		fmt.Fprintf(w, "mumble_command_rate_limited_total{command=%q} %v\n", cmd, n)
+	}
 	for i, u := range api.getUsers() {
 		writeMetric(w, i, u, "stats_connection_time_seconds", u.Stats.Connected)
 		writeMetric(w, i, u, "stats_ping_tcp_count", u.Stats.Ping.TCP.Packets)
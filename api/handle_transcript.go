@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/silkeh/mumble_bot/bot"
+)
+
+type Transcript struct {
+	Active      bool
+	Transcripts []*bot.Transcript
+}
+
+func (api *API) handleTranscript(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		WriteMethodNotAllowed(w)
+		return
+	}
+
+	sender, err := api.sender(req)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err := api.client.CheckTranscriptPermission(api.client.NewContext(bot.SourceAPI, sender)); err != nil {
+		WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var since time.Time
+	if s := req.URL.Query().Get("since"); s != "" {
+		since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	transcript := &Transcript{
+		Active:      api.client.Transcribing(),
+		Transcripts: api.client.Transcripts(since),
+	}
+	if transcript.Transcripts == nil {
+		transcript.Transcripts = []*bot.Transcript{}
+	}
+
+	err = json.NewEncoder(w).Encode(transcript)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
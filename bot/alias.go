@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// maxCommandDepth is the maximum number of nested aliases and Starlark commands.
+const maxCommandDepth = 10
+
+// checkChain returns an error result if a command or alias is already part of
+// the chain of aliases and Starlark commands that executed it,
+// or if the chain is nested too deeply.
+func checkChain(chain []string, name string) *Result {
+	for _, a := range chain {
+		if a == name {
+			return Errorf("Error: command loop: %s -&gt; %s", html.EscapeString(strings.Join(chain, " -> ")), html.EscapeString(name))
+		}
+	}
+	if len(chain) >= maxCommandDepth {
+		return Errorf("Error: commands nested deeper than %v: %s", maxCommandDepth, html.EscapeString(strings.Join(chain, " -> ")))
+	}
+	return nil
+}
+
+// extendChain returns a copy of a chain of aliases and Starlark commands with a name added.
+func extendChain(chain []string, name string) []string {
+	extended := make([]string, len(chain), len(chain)+1)
+	copy(extended, chain)
+	return append(extended, name)
+}
+
+// handleAlias expands and handles an alias.
+// The chain of aliases and Starlark commands that resulted in this alias is used to detect loops.
+func (c *Client) handleAlias(ctx *Context, aliases []string, name, alias string, args []string) *Result {
+	if resp := checkChain(aliases, name); resp != nil {
+		return resp
+	}
+
+	cmd, err := expandAlias(alias, args, c.aliasVariables(ctx))
+	if err != nil {
+		return Usagef("Error: alias %q: %s", html.EscapeString(name), html.EscapeString(err.Error()))
+	}
+
+	return c.handleSequence(ctx, cmd, extendChain(aliases, name))
+}
+
+// aliasVariables returns the named variables available in an alias in a context.
+func (c *Client) aliasVariables(ctx *Context) map[string]string {
+	vars := map[string]string{"user": "", "channel": ctx.ChannelName()}
+	if ctx.Sender != nil {
+		vars["user"] = ctx.Sender.Name
+	}
+	return vars
+}
+
+// expandAlias substitutes the placeholders in an alias template:
+//
+//	$1 ... $9, ${10}  the argument at the given position
+//	$@                all arguments
+//	$user, $channel   the name of the sender and their channel
+//	${name:-default}  the value of a placeholder, or the default if it is empty
+//	$$                a literal dollar sign
+//
+// Substituted values are quoted, so they are treated as single arguments.
+// Arguments are appended to the alias if it does not contain any placeholders.
+func expandAlias(alias string, args []string, vars map[string]string) (string, error) {
+	var out strings.Builder
+	substituted := false
+
+	for i := 0; i < len(alias); i++ {
+		if alias[i] != '$' || i+1 == len(alias) {
+			out.WriteByte(alias[i])
+			continue
+		}
+
+		var name, def string
+		hasDefault := false
+		switch next := alias[i+1]; {
+		case next == '$':
+			out.WriteByte('$')
+			i++
+			continue
+		case next == '@':
+			name = "@"
+			i++
+		case next == '{':
+			end := strings.IndexByte(alias[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder: %s", alias[i:])
+			}
+			name = alias[i+2 : i+end]
+			if n := strings.Index(name, ":-"); n >= 0 {
+				name, def, hasDefault = name[:n], name[n+2:], true
+			}
+			i += end
+		case isDigit(next):
+			name = alias[i+1 : i+2]
+			i++
+		case isLetter(next):
+			j := i + 1
+			for j < len(alias) && isLetter(alias[j]) {
+				j++
+			}
+			name = alias[i+1 : j]
+			i = j - 1
+		default:
+			out.WriteByte('$')
+			continue
+		}
+
+		values, err := aliasValue(name, args, vars)
+		if err != nil {
+			return "", err
+		}
+		substituted = true
+
+		switch {
+		case len(values) > 0:
+			out.WriteString(quoteWords(values))
+		case hasDefault:
+			out.WriteString(def)
+		}
+	}
+
+	if !substituted && len(args) > 0 {
+		out.WriteString(" " + quoteWords(args))
+	}
+
+	return out.String(), nil
+}
+
+// aliasValue returns the value of a placeholder, or nil if it is empty.
+func aliasValue(name string, args []string, vars map[string]string) ([]string, error) {
+	if name == "@" {
+		return args, nil
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 {
+			return nil, fmt.Errorf("invalid argument placeholder: $%s", name)
+		}
+		if n > len(args) || args[n-1] == "" {
+			return nil, nil
+		}
+		return args[n-1 : n], nil
+	}
+
+	v, ok := vars[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown placeholder: $%s", name)
+	}
+	if v == "" {
+		return nil, nil
+	}
+	return []string{v}, nil
+}
+
+// isDigit returns true if the character is an ASCII digit.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// isLetter returns true if the character is an ASCII letter.
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
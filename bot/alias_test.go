@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandAlias(t *testing.T) {
+	vars := map[string]string{"user": "alice", "channel": "Lobby", "empty": ""}
+	tests := []struct {
+		alias string
+		args  []string
+		out   string
+	}{
+		{`!play intro`, nil, `!play intro`},
+		{`!play`, []string{"a b", "c"}, `!play 'a b' c`},
+		{`!say $1 and $2`, []string{"a", "b c"}, `!say a and 'b c'`},
+		{`!say $@`, []string{"a", "b c"}, `!say a 'b c'`},
+		{`!say $@`, nil, `!say `},
+		{`!say ${10}`, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "ten"}, `!say ten`},
+		{`!say $2`, []string{"a"}, `!say `},
+		{`!say ${1:-hello}`, nil, `!say hello`},
+		{`!say ${1:-hello}`, []string{"bye"}, `!say bye`},
+		{`!say ${1:-hello}`, []string{""}, `!say hello`},
+		{`!say ${1:-}`, nil, `!say `},
+		{`!say ${empty:-none}`, nil, `!say none`},
+		{`!say ${user:-nobody}`, nil, `!say alice`},
+		{`!say $user in $channel`, nil, `!say alice in Lobby`},
+		{`!say $1`, []string{"it's"}, `!say 'it'\''s'`},
+		{`!say $1`, []string{"a; !stop"}, `!say 'a; !stop'`},
+		{`!say $$1`, []string{"a"}, `!say $1 a`},
+		{`!say $ $`, nil, `!say $ $`},
+	}
+
+	for _, tt := range tests {
+		out, err := expandAlias(tt.alias, tt.args, vars)
+		if err != nil {
+			t.Errorf("expandAlias(%q, %q) returned error: %s", tt.alias, tt.args, err)
+			continue
+		}
+		if out != tt.out {
+			t.Errorf("expandAlias(%q, %q) = %q, expected %q", tt.alias, tt.args, out, tt.out)
+		}
+	}
+}
+
+func TestExpandAliasErrors(t *testing.T) {
+	vars := map[string]string{"user": "alice"}
+	for _, alias := range []string{`!say ${1`, `!say $0`, `!say ${0:-a}`, `!say $unknown`, `!say ${x:-y}`} {
+		if out, err := expandAlias(alias, nil, vars); err == nil {
+			t.Errorf("expandAlias(%q) = %q, expected an error", alias, out)
+		}
+	}
+}
+
+func TestCheckChain(t *testing.T) {
+	deep := make([]string, maxCommandDepth)
+	for i := range deep {
+		deep[i] = string(rune('a' + i))
+	}
+
+	tests := []struct {
+		chain  []string
+		name   string
+		errors string
+	}{
+		{nil, "a", ""},
+		{[]string{"a", "b"}, "c", ""},
+		{[]string{"a", "b"}, "a", "command loop: a -&gt; b -&gt; a"},
+		{[]string{"a"}, "a", "command loop: a -&gt; a"},
+		{deep[:maxCommandDepth-1], "z", ""},
+		{deep, "z", "nested deeper than"},
+	}
+
+	for _, tt := range tests {
+		r := checkChain(tt.chain, tt.name)
+		switch {
+		case tt.errors == "" && r != nil:
+			t.Errorf("checkChain(%q, %q) = %q, expected no error", tt.chain, tt.name, r.HTML)
+		case tt.errors != "" && (r == nil || r.Status != StatusError || !strings.Contains(r.HTML, tt.errors)):
+			t.Errorf("checkChain(%q, %q) = %v, expected an error containing %q", tt.chain, tt.name, r, tt.errors)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// toneSound is the name of the notification sound that plays a tone instead of a clip.
+	toneSound = "tone"
+
+	// toneFrequency is the frequency of the tone in Hz.
+	toneFrequency = 880
+
+	// toneDuration is the duration of the tone.
+	toneDuration = 500 * time.Millisecond
+
+	// toneAmplitude is the amplitude of the tone.
+	toneAmplitude = 8192
+
+	// toneSampleRate is the sample rate of the generated audio.
+	toneSampleRate = 48000
+)
+
+// toneStream is an AudioStream containing a sine wave with a short fade in and out.
+type toneStream struct {
+	n, length int
+}
+
+// NewTone returns an AudioStream containing a short tone.
+func NewTone() AudioStream {
+	return &toneStream{length: int(toneDuration.Seconds() * toneSampleRate)}
+}
+
+// Read a number of 16-bit PCM samples from the stream.
+// Returns the number of decoded samples.
+func (t *toneStream) Read(pcm []int16) (int, error) {
+	fade := t.length / 10
+	i := 0
+	for ; i < len(pcm) && t.n < t.length; i, t.n = i+1, t.n+1 {
+		gain := 1.0
+		switch {
+		case t.n < fade:
+			gain = float64(t.n) / float64(fade)
+		case t.n > t.length-fade:
+			gain = float64(t.length-t.n) / float64(fade)
+		}
+
+		phase := 2 * math.Pi * toneFrequency * float64(t.n) / toneSampleRate
+		pcm[i] = int16(gain * toneAmplitude * math.Sin(phase))
+	}
+
+	if t.n >= t.length {
+		return i, io.EOF
+	}
+	return i, nil
+}
+
+// Close closes the stream.
+func (t *toneStream) Close() error {
+	return nil
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditSubject is the permission subject for querying the audit log.
+const auditSubject = "audit"
+
+// AuditEntry represents an executed command in the audit log.
+type AuditEntry struct {
+	Time     time.Time
+	Platform string
+	Sender   string
+	Hash     string `json:",omitempty"`
+	Channel  string
+	Command  string
+	Args     []string
+	Status   Status
+	Duration float64 // in seconds
+}
+
+// auditLog is a JSON-lines log of executed commands.
+// The log is rotated when it exceeds its maximum size,
+// keeping a maximum number of rotated files as `<file>.1`, `<file>.2`, etc.
+type auditLog struct {
+	sync.Mutex
+	config *AuditConfig
+	file   *os.File
+	size   int64
+}
+
+// newAuditLog opens or creates an audit log.
+func newAuditLog(config *AuditConfig) (*auditLog, error) {
+	l := &auditLog{config: config}
+	return l, l.open()
+}
+
+// open opens the current log file for appending.
+func (l *auditLog) open() (err error) {
+	l.file, err = os.OpenFile(l.config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return
+	}
+
+	info, err := l.file.Stat()
+	if err != nil {
+		return
+	}
+	l.size = info.Size()
+	return
+}
+
+// rotate rotates the log files and opens a new log file.
+func (l *auditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	for i := l.config.MaxFiles - 1; i > 0; i-- {
+		os.Rename(l.rotatedFile(i), l.rotatedFile(i+1))
+	}
+	if l.config.MaxFiles > 0 {
+		if err := os.Rename(l.config.File, l.rotatedFile(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.config.File); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+// rotatedFile returns the name of a rotated log file.
+func (l *auditLog) rotatedFile(n int) string {
+	return fmt.Sprintf("%s.%v", l.config.File, n)
+}
+
+// Write appends an entry to the log.
+func (l *auditLog) Write(e *AuditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.size > 0 && l.size+int64(len(line)) > l.config.MaxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("rotating audit log: %w", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Query returns the entries between the given times, optionally filtered by sender.
+// Zero times are not used for filtering.
+// The files are scanned without holding the lock, up to the size of the log when the query started.
+func (l *auditLog) Query(since, until time.Time, sender string) ([]*AuditEntry, error) {
+	l.Lock()
+	names := make([]string, 0, l.config.MaxFiles+1)
+	for i := l.config.MaxFiles; i > 0; i-- {
+		names = append(names, l.rotatedFile(i))
+	}
+	names = append(names, l.config.File)
+	size := l.size
+	l.Unlock()
+
+	entries := make([]*AuditEntry, 0)
+	for i, name := range names {
+		limit := int64(-1)
+		if i == len(names)-1 {
+			limit = size
+		}
+
+		err := scanAuditFile(name, limit, func(e *AuditEntry) {
+			if (!since.IsZero() && e.Time.Before(since)) ||
+				(!until.IsZero() && e.Time.After(until)) ||
+				(sender != "" && !strings.EqualFold(e.Sender, sender)) {
+				return
+			}
+			entries = append(entries, e)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// scanAuditFile calls fn for every entry in an audit log file, reading at most limit bytes if it is not negative.
+// Missing files are skipped, as they may have been rotated away.
+func scanAuditFile(name string, limit int64, fn func(*AuditEntry)) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e := new(AuditEntry)
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	return scanner.Err()
+}
+
+// audit records the execution of a command in the audit log, if enabled.
+func (c *Client) audit(ctx *Context, cmd string, args []string, status Status, start time.Time) {
+	if c.auditLog == nil {
+		return
+	}
+
+	e := &AuditEntry{
+		Time:     start,
+		Platform: string(ctx.Source),
+		Channel:  ctx.ChannelName(),
+		Command:  cmd,
+		Args:     args,
+		Status:   status,
+		Duration: time.Since(start).Seconds(),
+	}
+	if s := ctx.Sender; s != nil {
+		e.Sender = s.Name
+		e.Hash = s.Hash
+	}
+
+	if err := c.auditLog.Write(e); err != nil {
+		log.Printf("Error writing audit log: %s", err)
+	}
+}
+
+// CheckAuditPermission returns an error if the sender of a context is not allowed to query the audit log.
+func (c *Client) CheckAuditPermission(ctx *Context) error {
+	return c.checkPermission(ctx, auditSubject)
+}
+
+// AuditLog returns the audit log entries between the given times,
+// optionally filtered by the name of the sender.
+func (c *Client) AuditLog(since, until time.Time, sender string) ([]*AuditEntry, error) {
+	if c.auditLog == nil {
+		return nil, fmt.Errorf("audit log is not enabled")
+	}
+	return c.auditLog.Query(since, until, sender)
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"html"
+	"log"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// joinChannel moves the bot into a channel.
+func (c *Client) joinChannel(channel *gumble.Channel) error {
+	if err := checkChannelPermission(channel, gumble.PermissionEnter, "enter"); err != nil {
+		return err
+	}
+	if c.Mumble.Self.Channel != channel {
+		c.Mumble.Self.Move(channel)
+	}
+	return nil
+}
+
+// joinStartChannel moves the bot into the configured channel,
+// and starts following the configured user.
+func (c *Client) joinStartChannel() {
+	if name := c.Config.Mumble.Channel; name != "" {
+		channel := c.Mumble.FindChannel(name)
+		if channel == nil {
+			log.Printf("Unable to join unknown channel %q", name)
+		} else if err := c.joinChannel(channel); err != nil {
+			log.Printf("Unable to join channel %q: %s", name, err)
+		}
+	}
+
+	if name := c.Config.Mumble.Follow; name != "" {
+		c.Lock()
+		c.following = name
+		c.Unlock()
+		if u := c.Mumble.FindUser(name); u != nil {
+			c.followUser(u)
+		}
+	}
+}
+
+// followUser moves the bot into the channel of a user if the bot is following them.
+func (c *Client) followUser(u *gumble.User) {
+	c.Lock()
+	following := c.following
+	c.Unlock()
+
+	if following == "" || !strings.EqualFold(u.Name, following) || u.Channel == nil {
+		return
+	}
+	if err := c.joinChannel(u.Channel); err != nil {
+		log.Printf("Unable to follow %q: %s", u.Name, err)
+	}
+}
+
+// stopFollowing stops following a user, and returns the name of the user that was followed.
+func (c *Client) stopFollowing() string {
+	c.Lock()
+	defer c.Unlock()
+
+	following := c.following
+	c.following = ""
+	return following
+}
+
+// CommandJoin moves the bot into a channel, and stops following any user.
+func CommandJoin(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) != 1 {
+		return Usagef("Usage: %s &lt;channel&gt;", cmd)
+	}
+
+	channel := c.Mumble.FindChannel(args[0])
+	if channel == nil {
+		return Errorf("Error: unknown channel %q", html.EscapeString(args[0]))
+	}
+	if err := c.joinChannel(channel); err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	c.stopFollowing()
+	return Replyf("Joined %s", html.EscapeString(channel.Name))
+}
+
+// CommandCome moves the bot into the channel of the sender, and stops following any user.
+func CommandCome(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) != 0 {
+		return Usagef("Usage: %s", cmd)
+	}
+	if ctx.Sender == nil || ctx.Sender.User == nil || ctx.Sender.User.Channel == nil {
+		return Errorf("Error: only Mumble users can call the bot to their channel")
+	}
+
+	channel := ctx.Sender.User.Channel
+	if err := c.joinChannel(channel); err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	c.stopFollowing()
+	return Replyf("Joined %s", html.EscapeString(channel.Name))
+}
+
+// CommandFollow makes the bot follow a user, or the sender, as they move between channels.
+func CommandFollow(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	var u *gumble.User
+	switch {
+	case len(args) > 1:
+		return Usagef("Usage: %s [user]", cmd)
+	case len(args) == 1:
+		u = c.Mumble.FindUser(args[0])
+		if u == nil {
+			return Errorf("Error: unknown user %q", html.EscapeString(args[0]))
+		}
+	case ctx.Sender != nil && ctx.Sender.User != nil:
+		u = ctx.Sender.User
+	default:
+		return Usagef("Usage: %s &lt;user&gt;", cmd)
+	}
+	if u == c.Mumble.Self {
+		return Errorf("Error: the bot can not follow itself")
+	}
+
+	c.Lock()
+	c.following = u.Name
+	c.Unlock()
+
+	c.followUser(u)
+	return Replyf("Following %s", html.EscapeString(u.Name))
+}
+
+// CommandUnfollow stops following a user.
+func CommandUnfollow(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	following := c.stopFollowing()
+	if following == "" {
+		return Errorf("Error: not following anyone")
+	}
+	return Replyf("Stopped following %s", html.EscapeString(following))
+}
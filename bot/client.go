@@ -2,10 +2,14 @@ package bot
 
 import (
 	"fmt"
+	"html"
 	"io"
+	"log"
 	"math"
+	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/silkeh/mumble_bot/matrix"
 	"github.com/silkeh/mumble_bot/mumble"
@@ -20,8 +24,28 @@ type Client struct {
 	Mumble   *mumble.Client
 	Matrix   *matrix.Client
 	Telegram *telegram.Client
-	commands map[string]CommandHandler
+	commands map[string]*Command
 	volume   int8
+	playing  int
+	scripts  int
+
+	plugins       []string
+	starlark      []string
+	starlarkHooks starlarkHooks
+	starlarkQueue chan *starlarkHookCall
+	reloads       chan *Config
+	transcription *transcription
+	loudness      *loudness
+	rateLimiter   *rateLimiter
+	auditLog      *auditLog
+	poll          *poll
+	reminders     *reminders
+	catalogs      map[string]Catalog
+	languages     *languages
+	initiatives   map[string]*initiative
+	rolls         rollHistory
+	teamMoves     []teamMove
+	following     string
 }
 
 const (
@@ -35,18 +59,40 @@ const (
 	DefaultVolume = -18
 )
 
+// audioPollInterval is the interval at which WaitAudio checks if audio has finished.
+const audioPollInterval = 50 * time.Millisecond
+
 const (
 	joinHook       = "join"
 	leaveHook      = "leave"
 	firstJoinHook  = "first_join"
 	lastLeaveHook  = "last_leave"
+	loudUserHook   = "loud_user"
 	defaultSubject = "default"
 )
 
 // NewClient initializes the client with a given config.
 // Either Matrix or Telegram may be configured, not both at the same time.
 func NewClient(config *Config) (c *Client, err error) {
-	c = &Client{Config: config, volume: DefaultVolume, commands: defaultCommands}
+	c = &Client{
+		Config:        config,
+		volume:        DefaultVolume,
+		commands:      make(map[string]*Command, len(defaultCommands)),
+		rateLimiter:   newRateLimiter(),
+		starlarkQueue: make(chan *starlarkHookCall, starlarkQueueSize),
+		reloads:       make(chan *Config),
+	}
+	for name, cmd := range defaultCommands {
+		c.commands[name] = cmd
+	}
+
+	// Audit log
+	if config.Audit != nil {
+		c.auditLog, err = newAuditLog(config.Audit)
+		if err != nil {
+			return nil, fmt.Errorf("opening audit log: %w", err)
+		}
+	}
 
 	// Check if Matrix and Telegram aren't enabled at the same time.
 	if config.Telegram != nil && config.Matrix != nil {
@@ -68,6 +114,7 @@ func NewClient(config *Config) (c *Client, err error) {
 		if err != nil {
 			return nil, fmt.Errorf("connecting to Matrix: %w", err)
 		}
+		go c.Matrix.Sync()
 	}
 
 	// Mumble
@@ -75,74 +122,271 @@ func NewClient(config *Config) (c *Client, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("connecting to Mumble: %w", err)
 	}
+	c.joinStartChannel()
+	if config.Mumble.usesGroups() {
+		go c.Mumble.RefreshGroups()
+	}
+
+	// Languages
+	if err := c.LoadCatalogs(); err != nil {
+		log.Printf("Error loading message catalogs: %s", err)
+	}
+	if err := c.loadLanguages(); err != nil {
+		log.Printf("Error loading language preferences: %s", err)
+	}
+
+	// Reminders
+	if err := c.loadReminders(); err != nil {
+		log.Printf("Error loading reminders: %s", err)
+	}
+
+	// Plugins
+	if err := c.LoadPlugins(); err != nil {
+		log.Printf("Error loading plugins: %s", err)
+	}
+
+	// Starlark
+	if err := c.LoadStarlark(); err != nil {
+		log.Printf("Error loading Starlark scripts: %s", err)
+	}
+	go c.runStarlarkHooks()
+
+	// Transcription
+	if config.Transcription != nil {
+		c.transcription = newTranscription(c, config.Transcription)
+		c.Mumble.Audio.AddHandler(c.transcription)
+		if config.Transcription.Enabled {
+			c.StartTranscription()
+		}
+	}
+
+	// Loudness alerts
+	if config.Mumble.Loudness != nil {
+		c.loudness = newLoudness(c)
+		c.Mumble.Audio.AddHandler(c.loudness)
+		c.updateListening()
+	}
 
 	return
 }
 
 // Run the client.
 func (c *Client) Run() error {
+	var matrixMessages chan *matrix.Message
+	if c.Matrix != nil {
+		matrixMessages = c.Matrix.Messages
+	}
+	var telegramMessages chan *telegram.Message
+	if c.Telegram != nil {
+		telegramMessages = c.Telegram.Messages
+	}
+
 	for {
 		select {
 		case e := <-c.Mumble.UserChanges:
 			c.handleUserChange(e)
 		case msg := <-c.Mumble.Messages:
 			c.handleTextMessage(msg)
+		case msg := <-matrixMessages:
+			c.handleChatMessage(SourceMatrix, msg.Sender, msg.Sender, msg.Text)
+		case msg := <-telegramMessages:
+			c.handleChatMessage(SourceTelegram, msg.Sender, msg.Name, msg.Text)
+		case config := <-c.reloads:
+			c.reload(config)
 		}
 	}
 }
 
+// Reload replaces the configuration, and reloads the message catalogs, plugins and Starlark scripts.
+// The configuration is replaced by the event loop, so Run must be running.
+func (c *Client) Reload(config *Config) {
+	c.reloads <- config
+}
+
+// reload replaces the configuration and reloads everything that depends on it.
+func (c *Client) reload(config *Config) {
+	c.Config = config
+	if err := c.LoadCatalogs(); err != nil {
+		log.Printf("Error reloading message catalogs: %s", err)
+	}
+	if err := c.LoadPlugins(); err != nil {
+		log.Printf("Error reloading plugins: %s", err)
+	}
+	if err := c.LoadStarlark(); err != nil {
+		log.Printf("Error reloading Starlark scripts: %s", err)
+	}
+}
+
 func (c *Client) handleUserChange(e *gumble.UserChangeEvent) {
 	switch {
 	case e.Type.Has(gumble.UserChangeConnected):
 		if len(c.Mumble.Users) == 2 {
-			c.ExecuteHook(firstJoinHook, e.User.Name)
+			c.ExecuteHook(firstJoinHook, c.NewContext(SourceHook, MumbleSender(e.User)))
 		}
-		c.ExecuteHook(joinHook, e.User.Name)
+		c.ExecuteHook(joinHook, c.NewContext(SourceHook, MumbleSender(e.User)))
+		c.deliverReminders(e.User)
+		c.followUser(e.User)
 	case e.Type.Has(gumble.UserChangeDisconnected):
 		if len(c.Mumble.Users) == 1 {
-			c.ExecuteHook(lastLeaveHook, e.User.Name)
+			c.ExecuteHook(lastLeaveHook, c.NewContext(SourceHook, MumbleSender(e.User)))
 		}
-		c.ExecuteHook(leaveHook, e.User.Name)
+		c.ExecuteHook(leaveHook, c.NewContext(SourceHook, MumbleSender(e.User)))
+		if c.loudness != nil {
+			c.loudness.remove(e.User.Session)
+		}
+	case e.Type.Has(gumble.UserChangeChannel):
+		c.followUser(e.User)
 	}
 }
 
 func (c *Client) handleTextMessage(e *gumble.TextMessage) {
-	if !strings.HasPrefix(e.Message, c.Config.Mumble.CommandPrefix) {
+	msg := decodeMessage(e.Message)
+	if e.Sender == nil || !strings.HasPrefix(msg, c.Config.Mumble.CommandPrefix) {
+		return
+	}
+
+	ctx := MumbleContext(e)
+	c.SendReply(ctx, c.HandleCommand(ctx, strings.TrimPrefix(msg, c.Config.Mumble.CommandPrefix)))
+}
+
+// handleChatMessage handles a text message from a Matrix or Telegram user.
+// Replies to commands are sent to the chat.
+func (c *Client) handleChatMessage(source Source, id, name, text string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, c.Config.Mumble.CommandPrefix) {
 		return
 	}
 
-	res := c.HandleCommand(strings.TrimPrefix(e.Message, c.Config.Mumble.CommandPrefix))
-	if res != "" {
-		c.Mumble.SendTextResponse(e, res)
+	ctx := c.NewContext(source, ChatSender(id, name))
+	c.SendReply(ctx, c.HandleCommand(ctx, strings.TrimPrefix(text, c.Config.Mumble.CommandPrefix)))
+}
+
+// HandleCommand handles a bot command issued in the given context.
+// The result is never nil.
+func (c *Client) HandleCommand(ctx *Context, s string) *Result {
+	return c.handleCommand(ctx, s, nil).localize(c.catalog(ctx))
+}
+
+// handleCommand handles a bot command,
+// resulting from the expansion of the given chain of aliases.
+// The route of the reply is set according to the configuration.
+func (c *Client) handleCommand(ctx *Context, s string, aliases []string) *Result {
+	cmd, args, err := parseCommand(s)
+	if err != nil {
+		resp := Usagef("Error: %s", html.EscapeString(err.Error()))
+		resp.route = c.replyRoute("", resp)
+		return resp
+	}
+
+	resp := c.runCommand(ctx, cmd, args, aliases)
+	resp.route = c.replyRoute(cmd, resp)
+	return resp
+}
+
+// runCommand runs a parsed bot command, or the alias with the name of the command.
+func (c *Client) runCommand(ctx *Context, cmd string, args []string, aliases []string) *Result {
+	start := time.Now()
+	if command := c.Command(cmd); command != nil {
+		if err := c.checkPermission(ctx, cmd); err != nil {
+			c.audit(ctx, cmd, args, StatusDenied, start)
+			return newResult(StatusDenied, "Permission denied: %s", html.EscapeString(err.Error()))
+		}
+		if err := c.checkRateLimit(ctx, cmd); err != nil {
+			c.audit(ctx, cmd, args, StatusRateLimited, start)
+			return newResult(StatusRateLimited, "Slow down, %s", html.EscapeString(err.Error()))
+		}
+
+		resp := command.Handler(c, ctx, cmd, args...)
+		if resp == nil {
+			resp = Reply("")
+		}
+		c.audit(ctx, cmd, args, resp.Status, start)
+		return resp
+	}
+
+	if _, ok := c.Config.Mumble.Alias[cmd]; !ok {
+		c.audit(ctx, cmd, args, StatusUnknown, start)
 	}
+	return commandDefault(c, ctx, aliases, cmd, args...)
 }
 
-// HandleCommand handles a bot command.
-func (c *Client) HandleCommand(s string) string {
-	cmd, args := parseCommand(s)
-	if f, ok := c.commands[cmd]; ok {
-		return f(c, cmd, args...)
+// replyRoute returns the route of the reply to a command.
+// Usage replies and commands with a configured route take precedence over
+// the route of the expansion of an alias, and the default route.
+func (c *Client) replyRoute(cmd string, r *Result) string {
+	config := &c.Config.Mumble.Replies
+	switch {
+	case r.Status == StatusInvalid && config.Usage != "":
+		return config.Usage
+	case config.Commands[cmd] != "":
+		return config.Commands[cmd]
+	case r.route != "":
+		return r.route
+	default:
+		return config.Default
 	}
+}
+
+// RegisterCommand registers a command under the given name,
+// replacing any existing command with the same name.
+func (c *Client) RegisterCommand(name string, cmd *Command) {
+	c.Lock()
+	defer c.Unlock()
+	c.commands[name] = cmd
+}
+
+// Command returns the command registered under the given name, or nil if it does not exist.
+func (c *Client) Command(name string) *Command {
+	c.Lock()
+	defer c.Unlock()
+	return c.commands[name]
+}
+
+// Commands returns all registered commands by name.
+func (c *Client) Commands() map[string]*Command {
+	c.Lock()
+	defer c.Unlock()
 
-	return commandDefault(c, cmd, args...)
+	commands := make(map[string]*Command, len(c.commands))
+	for name, cmd := range c.commands {
+		commands[name] = cmd
+	}
+	return commands
 }
 
-// ExecuteHook executes a configured hook.
-func (c *Client) ExecuteHook(name, subject string) string {
+// ExecuteHook executes a configured hook in the context that triggered it.
+// The hook for the name of the sender is executed, or the default hook if none is configured.
+// Starlark hooks are queued, and executed in order outside of the event loop.
+func (c *Client) ExecuteHook(name string, ctx *Context) *Result {
+	c.queueStarlarkHooks(name, ctx)
+
 	actions, ok := c.Config.Mumble.Hooks[name]
 	if !ok {
-		return ""
+		return Reply("")
 	}
 
-	command, ok := actions[subject]
+	var command string
+	if ctx.Sender != nil {
+		command, ok = actions[ctx.Sender.Name]
+	}
 	if !ok {
 		command, ok = actions[defaultSubject]
 		if !ok {
-			return ""
+			return Reply("")
 		}
 	}
 
-	return c.HandleCommand(command)
+	return c.HandleSequence(ctx, command)
+}
+
+// updateListening undeafens the Mumble client if received audio is used.
+func (c *Client) updateListening() {
+	if c.loudness != nil || c.Transcribing() {
+		c.Mumble.SetSelfDeafened(false)
+		c.Mumble.SetSelfMuted(true)
+	} else {
+		c.Mumble.SetSelfDeafened(true)
+	}
 }
 
 // SendSticker sends a sticker to a either Matrix or Telegram.
@@ -172,6 +416,45 @@ func (c *Client) SendSticker(name string) error {
 	return nil
 }
 
+// SendText sends a text message to either Matrix or Telegram.
+func (c *Client) SendText(text string) error {
+	if c.Telegram != nil {
+		_, err := c.Telegram.SendText(text)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Matrix != nil {
+		_, err := c.Matrix.SendText(text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendMessage sends a formatted message to either Matrix or Telegram,
+// rendered in the format of each platform.
+func (c *Client) SendMessage(m *Message) error {
+	if c.Telegram != nil {
+		_, err := c.Telegram.SendHTML(m.Telegram())
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Matrix != nil {
+		_, err := c.Matrix.SendHTML(m.Text(), m.HTML())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SetVolume sets the volume of any Mumble audio played.
 func (c *Client) SetVolume(n int8) {
 	c.Lock()
@@ -218,13 +501,65 @@ func (c *Client) playFile(path string, count int) error {
 		return err
 	}
 
-	// Play the loop in separate threads
-	ch := make(chan int16)
-	go c.Mumble.StreamAudio(ch)
-	go c.playRaw(ch, NewAudioLoop(fh, count))
+	c.playStream(NewAudioLoop(fh, count))
 	return nil
 }
 
+// PlayTone plays a short notification tone.
+func (c *Client) PlayTone() {
+	c.playStream(NewTone())
+}
+
+// PlayNotification plays a notification sound,
+// which is either a short tone for "tone", or the name of a clip.
+// Nothing is played if the sound is empty.
+func (c *Client) PlayNotification(sound string) {
+	switch sound {
+	case "":
+	case toneSound:
+		c.PlayTone()
+	default:
+		file := path.Join(c.Config.Mumble.Sounds.Clips, sound+SoundExtension)
+		if err := c.PlaySound(file); err != nil {
+			log.Printf("Error playing notification sound %q: %s", sound, err)
+		}
+	}
+}
+
+// playStream plays an AudioStream.
+func (c *Client) playStream(stream AudioStream) {
+	// Play the stream in separate threads
+	c.Lock()
+	c.playing++
+	c.Unlock()
+
+	ch := make(chan int16)
+	go func() {
+		c.Mumble.StreamAudio(ch)
+
+		c.Lock()
+		c.playing--
+		c.Unlock()
+	}()
+	go c.playRaw(ch, stream)
+}
+
+// Playing returns true if any audio is playing or queued.
+func (c *Client) Playing() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.playing > 0
+}
+
+// WaitAudio waits until all playing and queued audio has finished,
+// or until the timeout has passed.
+func (c *Client) WaitAudio(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for c.Playing() && time.Now().Before(deadline) {
+		time.Sleep(audioPollInterval)
+	}
+}
+
 // playRaw loops a byte containing 16-bit 48k PCM audio a number of times,
 // with volume adjusted on the fly.
 func (c *Client) playRaw(ch chan<- int16, stream AudioStream) {
@@ -3,44 +3,254 @@ package bot
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"html/template"
-	"os/exec"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/justinian/dice"
 )
 
 // CommandHandler is the function signature for a command handler.
-type CommandHandler func(c *Client, cmd string, args ...string) (resp string)
+type CommandHandler func(c *Client, ctx *Context, cmd string, args ...string) (resp *Result)
+
+// Command describes a command and its handler.
+type Command struct {
+	Handler  CommandHandler `json:"-"`
+	Summary  string
+	Args     []Argument
+	Examples []string
+}
+
+// Argument describes an argument of a command.
+type Argument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Optional    bool   `json:"optional"`
+	Repeated    bool   `json:"repeated"`
+}
+
+// Usage returns the usage of the command with the given name.
+func (c *Command) Usage(name string) string {
+	usage := name
+	for _, a := range c.Args {
+		arg := a.Name
+		if a.Repeated {
+			arg += "..."
+		}
+		if a.Optional {
+			usage += " [" + arg + "]"
+		} else {
+			usage += " <" + arg + ">"
+		}
+	}
+	return usage
+}
 
 // SoundExtension contains the filename extension for all sound files.
 const SoundExtension = ".opus"
 
-// commandHandlers contains handlers for given commands.
-var defaultCommands = map[string]CommandHandler{
-	"hold":     CommandHold,
-	"play":     CommandClip,
-	"volume":   CommandSetVolume,
-	"volume--": CommandDecreaseVolume,
-	"volume++": CommandIncreaseVolume,
-	"stop":     CommandStopAudio,
-	"sticker":  CommandSendSticker,
-	"roll":     CommandDiceRoll,
-	"shell":    CommandShell,
+// defaultCommands contains the commands that are available by default.
+var defaultCommands = map[string]*Command{
+	"help": {
+		Handler:  CommandHelp,
+		Summary:  "Show the available commands, or the usage of a command",
+		Args:     []Argument{{Name: "command", Description: "Command or alias to show the usage of", Optional: true}},
+		Examples: []string{"help", "help roll"},
+	},
+	"hold": {
+		Handler:  CommandHold,
+		Summary:  "Play a sound file in a loop, like hold music",
+		Args:     []Argument{{Name: "name", Description: "Name of the sound file"}},
+		Examples: []string{"hold elevator"},
+	},
+	"play": {
+		Handler:  CommandClip,
+		Summary:  "Play a sound clip once",
+		Args:     []Argument{{Name: "name", Description: "Name of the sound clip"}},
+		Examples: []string{"play welcome"},
+	},
+	"volume": {
+		Handler:  CommandSetVolume,
+		Summary:  "Show or set the volume of played audio",
+		Args:     []Argument{{Name: "volume", Description: fmt.Sprintf("Gain in dB, from %v to %v", MinVolume, MaxVolume), Optional: true}},
+		Examples: []string{"volume", "volume -20"},
+	},
+	"volume--": {
+		Handler: CommandDecreaseVolume,
+		Summary: "Decrease the volume of played audio by 3 dB",
+	},
+	"volume++": {
+		Handler: CommandIncreaseVolume,
+		Summary: "Increase the volume of played audio by 3 dB",
+	},
+	"stop": {
+		Handler: CommandStopAudio,
+		Summary: "Stop any playing audio",
+	},
+	"sticker": {
+		Handler:  CommandSendSticker,
+		Summary:  "Send a sticker to the linked chat",
+		Args:     []Argument{{Name: "sticker", Description: "Name of the sticker"}},
+		Examples: []string{"sticker welcome"},
+	},
+	"roll": {
+		Handler:  CommandDiceRoll,
+		Summary:  "Roll a set of dice",
+		Args:     []Argument{{Name: "description", Description: "Dice to roll, see https://github.com/justinian/dice for the syntax"}},
+		Examples: []string{"roll 4d20", "roll 4d6kh3", "roll 3d6v4"},
+	},
+	"odds": {
+		Handler:  CommandOdds,
+		Summary:  "Show the odds of a roll of dice",
+		Args:     []Argument{{Name: "description", Description: "Dice to roll, see https://github.com/justinian/dice for the syntax"}, {Name: "target", Description: "Target to compute the chance of, like >=15", Optional: true}},
+		Examples: []string{"odds 3d6", "odds 4d6kh3 >=15", "odds 5d10v8 >=3"},
+	},
+	"rolls": {
+		Handler:  CommandRolls,
+		Summary:  "Show the recent rolls of you or another user",
+		Args:     []Argument{{Name: "user", Description: "Name of the user", Optional: true}},
+		Examples: []string{"rolls", "rolls Alice"},
+	},
+	"init": {
+		Handler:  CommandInitiative,
+		Summary:  "Track the initiative order and turns of an encounter in the channel",
+		Args:     []Argument{{Name: "add|remove|next|list|clear", Description: "Action to perform"}, {Name: "name", Description: "Name of the combatant to add or remove", Optional: true}, {Name: "dice", Description: "Dice to roll for initiative", Optional: true}},
+		Examples: []string{"init add Goblin 1d20+2", "init next", "init list"},
+	},
+	"join": {
+		Handler:  CommandJoin,
+		Summary:  "Move the bot into a channel",
+		Args:     []Argument{{Name: "channel", Description: "Name or path of the channel"}},
+		Examples: []string{"join Lobby", `join "Games/Team red"`},
+	},
+	"come": {
+		Handler:  CommandCome,
+		Summary:  "Move the bot into your channel",
+		Examples: []string{"come"},
+	},
+	"follow": {
+		Handler:  CommandFollow,
+		Summary:  "Let the bot follow you or another user between channels",
+		Args:     []Argument{{Name: "user", Description: "Name of the user to follow, defaults to you", Optional: true}},
+		Examples: []string{"follow", "follow Alice"},
+	},
+	"unfollow": {
+		Handler:  CommandUnfollow,
+		Summary:  "Stop following a user",
+		Examples: []string{"unfollow"},
+	},
+	"teams": {
+		Handler:  CommandTeams,
+		Summary:  "Split the users in your channel into random teams",
+		Args:     []Argument{{Name: "number|undo", Description: "Number of teams, or undo to move everyone back"}, {Name: "channel", Description: "Channels to move the teams into, one per team", Optional: true, Repeated: true}},
+		Examples: []string{"teams 2", `teams 2 "Team red" "Team blue"`, "teams undo"},
+	},
+	"mute": {
+		Handler:  muteHandler(true),
+		Summary:  "Mute a user on the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}},
+		Examples: []string{"mute Alice"},
+	},
+	"unmute": {
+		Handler:  muteHandler(false),
+		Summary:  "Unmute a user on the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}},
+		Examples: []string{"unmute Alice"},
+	},
+	"deafen": {
+		Handler:  deafenHandler(true),
+		Summary:  "Deafen a user on the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}},
+		Examples: []string{"deafen Alice"},
+	},
+	"undeafen": {
+		Handler:  deafenHandler(false),
+		Summary:  "Undeafen a user on the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}},
+		Examples: []string{"undeafen Alice"},
+	},
+	"move": {
+		Handler:  CommandMove,
+		Summary:  "Move a user into a channel",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}, {Name: "channel", Description: "Name or path of the channel"}},
+		Examples: []string{"move Alice Lobby", `move Alice "Games/Team red"`},
+	},
+	"kick": {
+		Handler:  CommandKick,
+		Summary:  "Kick a user from the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}, {Name: "reason", Description: "Reason shown to the user", Optional: true, Repeated: true}},
+		Examples: []string{"kick Alice", "kick Alice spamming the channel"},
+	},
+	"ban": {
+		Handler:  CommandBan,
+		Summary:  "Ban a user from the server",
+		Args:     []Argument{{Name: "user", Description: "Name of the user"}, {Name: "reason", Description: "Reason shown to the user", Optional: true, Repeated: true}},
+		Examples: []string{"ban Alice", "ban Alice repeated abuse"},
+	},
+	"language": {
+		Handler:  CommandLanguage,
+		Summary:  "Show or set your preferred language",
+		Args:     []Argument{{Name: "language|default", Description: "Language to use, or default to use the default language", Optional: true}},
+		Examples: []string{"language", "language nl", "language default"},
+	},
+	"shell": {
+		Handler:  CommandShell,
+		Summary:  "Execute a script in the configured script directory",
+		Args:     []Argument{{Name: "script", Description: "Name of the script"}, {Name: "arguments", Description: "Arguments for the script", Optional: true, Repeated: true}},
+		Examples: []string{"shell uptime"},
+	},
+	"poll": {
+		Handler:  CommandPoll,
+		Summary:  "Start, show or close a poll",
+		Args:     []Argument{{Name: "duration", Description: "Time after which the poll closes", Optional: true}, {Name: "question", Description: "Question of the poll, or close to close the poll", Optional: true}, {Name: "options", Description: "Options to vote for", Optional: true, Repeated: true}},
+		Examples: []string{`poll "Which game?" chess go`, `poll 5m "Pizza?" yes no`, "poll close"},
+	},
+	"vote": {
+		Handler:  CommandVote,
+		Summary:  "Vote in the current poll",
+		Args:     []Argument{{Name: "number", Description: "Number of the option to vote for"}},
+		Examples: []string{"vote 2"},
+	},
+	"remind": {
+		Handler:  CommandRemind,
+		Summary:  "Send a reminder to you or the channel after a duration",
+		Args:     []Argument{{Name: "me|channel", Description: "Send the reminder to you (default) or the channel", Optional: true}, {Name: "duration", Description: "Time after which to send the reminder"}, {Name: "message", Description: "Message of the reminder", Repeated: true}},
+		Examples: []string{"remind 10m stretch break", "remind channel 1h pizza is ready"},
+	},
+	"timer": {
+		Handler:  CommandTimer,
+		Summary:  "Start a timer for you or the channel",
+		Args:     []Argument{{Name: "me|channel", Description: "Notify you (default) or the channel", Optional: true}, {Name: "duration", Description: "Duration of the timer"}, {Name: "label", Description: "Label of the timer", Optional: true, Repeated: true}},
+		Examples: []string{"timer 25m", "timer channel 5m break"},
+	},
+	"countdown": {
+		Handler:  CommandCountdown,
+		Summary:  "Count down in the channel",
+		Args:     []Argument{{Name: "seconds", Description: "Number of seconds to count down from"}},
+		Examples: []string{"countdown 10"},
+	},
+	"reminders": {
+		Handler:  CommandReminders,
+		Summary:  "List or cancel pending reminders, timers and countdowns",
+		Args:     []Argument{{Name: "cancel", Description: "Cancel a reminder", Optional: true}, {Name: "id", Description: "Number of the reminder to cancel", Optional: true}},
+		Examples: []string{"reminders", "reminders cancel 3"},
+	},
+	"transcript": {
+		Handler:  CommandTranscript,
+		Summary:  "Control the transcription of audio, or show the transcript",
+		Args:     []Argument{{Name: "start|stop|clear|lines", Description: "Action to perform, or the number of lines to show", Optional: true}},
+		Examples: []string{"transcript start", "transcript 20"},
+	},
 }
 
 var templates *template.Template
 
-type soundUsageParams struct {
-	Command string
-	Files   []string
-}
-
 var soundUsage = `
-Usage: {{.Command}} &lt;name&gt;<br/>
-Where &lt;name&gt; is one of:
+{{.Catalog.Translate "Usage:"}} {{.Command}} &lt;name&gt;<br/>
+{{.Catalog.Translate "Where <name> is one of:"}}
 <ul>
 {{range .Files}}
 <li>{{.}}</li>
@@ -53,108 +263,107 @@ func init() {
 }
 
 // CommandHold plays a given sound file in a loop (like hold music).
-func CommandHold(c *Client, cmd string, args ...string) (resp string) {
+func CommandHold(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	if len(args) < 1 {
-		return renderSoundUsage(cmd, c.Config.Mumble.Sounds.Hold)
+		return renderSoundUsage(c.catalog(ctx), cmd, c.Config.Mumble.Sounds.Hold)
 	}
 
 	name := strings.Join(args, " ")
 	file := path.Join(c.Config.Mumble.Sounds.Clips, name)
 	if err := c.PlayHold(file + SoundExtension); err != nil {
-		return fmt.Sprintf("Error playing hold music %q: %s", args[0], err)
+		return Errorf("Error playing hold music %q: %s", html.EscapeString(name), html.EscapeString(err.Error()))
 	}
-	return fmt.Sprintf("Please hold. Now playing %q...", name)
+	return Replyf("Please hold. Now playing %q...", html.EscapeString(name)).Attach(AttachmentSound, name)
 }
 
 // CommandClip plays a sound file once.
-func CommandClip(c *Client, cmd string, args ...string) (resp string) {
+func CommandClip(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	if len(args) < 1 {
-		return renderSoundUsage(cmd, c.Config.Mumble.Sounds.Clips)
+		return renderSoundUsage(c.catalog(ctx), cmd, c.Config.Mumble.Sounds.Clips)
 	}
 
 	name := strings.Join(args, " ")
 	file := path.Join(c.Config.Mumble.Sounds.Clips, name)
 	if err := c.PlaySound(file + SoundExtension); err != nil {
-		return fmt.Sprintf("Error playing music clip %q: %s", name, err)
+		return Errorf("Error playing music clip %q: %s", html.EscapeString(name), html.EscapeString(err.Error()))
 	}
-	return fmt.Sprintf("Now playing %q...", name)
+	return Replyf("Now playing %q...", html.EscapeString(name)).Attach(AttachmentSound, name)
 }
 
 // CommandSetVolume sets the volume of the bot to a given value.
-func CommandSetVolume(c *Client, cmd string, args ...string) (resp string) {
+func CommandSetVolume(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	if len(args) != 1 {
-		return fmt.Sprintf("Volume is %+v dB (max %+v dB)", c.Volume(), MaxVolume)
+		return Replyf("Volume is %+v dB (max %+v dB)", c.Volume(), MaxVolume)
 	}
 
 	v, err := strconv.ParseInt(args[0], 10, 8)
 	if err != nil || v > MaxVolume || v < MinVolume {
-		return fmt.Sprintf("Usage: %s %v-%v (in dB)", cmd, MinVolume, MaxVolume)
+		return Usagef("Usage: %s %v-%v (in dB)", cmd, MinVolume, MaxVolume)
 	}
 
 	c.SetVolume(int8(v % 256))
-	return fmt.Sprintf("Volume set to %+v dB", c.Volume())
+	return Replyf("Volume set to %+v dB", c.Volume())
 }
 
 // CommandDecreaseVolume decreases the volume by one step.
-func CommandDecreaseVolume(c *Client, cmd string, args ...string) (resp string) {
+func CommandDecreaseVolume(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	c.ChangeVolume(-3)
-	return fmt.Sprintf("Volume set to %+v dB", c.Volume())
+	return Replyf("Volume set to %+v dB", c.Volume())
 }
 
 // CommandIncreaseVolume increases the volume by one step.
-func CommandIncreaseVolume(c *Client, cmd string, args ...string) (resp string) {
+func CommandIncreaseVolume(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	c.ChangeVolume(3)
-	return fmt.Sprintf("Volume set to %+v dB", c.Volume())
+	return Replyf("Volume set to %+v dB", c.Volume())
 }
 
 // CommandStopAudio stops any playing audio.
-func CommandStopAudio(c *Client, cmd string, args ...string) (resp string) {
+func CommandStopAudio(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	c.Mumble.StopAudio()
 	return
 }
 
 // CommandSendSticker sends a sticker to a linked chat platform.
-func CommandSendSticker(c *Client, cmd string, args ...string) (resp string) {
+func CommandSendSticker(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	if len(args) != 1 {
-		return fmt.Sprintf("Usage: %s <sticker>", cmd)
+		return Usagef("Usage: %s &lt;sticker&gt;", cmd)
 	}
 
 	err := c.SendSticker(args[0])
 	if err != nil {
-		return fmt.Sprintf("Error: %s", err)
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
 	}
-	return
+	return Reply("").Attach(AttachmentSticker, args[0])
 }
 
-func commandDefault(c *Client, cmd string, args ...string) (resp string) {
+func commandDefault(c *Client, ctx *Context, aliases []string, cmd string, args ...string) (resp *Result) {
 	// Resolve any configured aliases
 	if alias, ok := c.Config.Mumble.Alias[cmd]; ok {
-		if len(args) > 0 {
-			alias += " " + strings.Join(args, " ")
-		}
-		return c.HandleCommand(alias)
+		return c.handleAlias(ctx, aliases, cmd, alias, args)
 	}
 
-	return fmt.Sprintf("Unknown command: %s", cmd)
+	return newResult(StatusUnknown, "Unknown command: %s", html.EscapeString(cmd))
 }
 
-func renderSoundUsage(command, path string) string {
+func renderSoundUsage(cat Catalog, command, path string) *Result {
 	files, err := listFiles(path, SoundExtension)
 	if err != nil {
-		return err.Error()
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
 	}
 	params := struct {
 		Command string
 		Files   []string
+		Catalog Catalog
 	}{
 		command,
 		files,
+		cat,
 	}
 	usage, err := renderTemplate("sound", params)
 	if err != nil {
-		return err.Error()
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
 	}
-	return usage
+	return Usagef("%s", usage)
 }
 
 func renderTemplate(name string, data interface{}) (string, error) {
@@ -164,69 +373,170 @@ func renderTemplate(name string, data interface{}) (string, error) {
 }
 
 // CommandDiceRoll rolls a (set of) dice and prints the result.
+// The result is added to the roll history of the sender.
 // See https://github.com/justinian/dice for features and syntax.
-func CommandDiceRoll(c *Client, cmd string, args ...string) (resp string) {
+func CommandDiceRoll(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
 	if len(args) != 1 {
-		return fmt.Sprintf("Usage: %s &lt;description&gt;<br/>Example: %s 4d20", cmd, cmd)
+		return Usagef("Usage: %s &lt;description&gt;<br/>Example: %s 4d20", cmd, cmd)
 	}
 
 	result, _, err := dice.Roll(args[0])
 	if err != nil {
-		return fmt.Sprintf("Error: %s", err)
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
 	}
 
-	resp = fmt.Sprintf("Rolled %s: ", args[0])
+	cat := c.catalog(ctx)
+	msg := cat.Sprintf("Rolled %s: ", html.EscapeString(args[0]))
 	switch r := result.(type) {
 	case dice.StdResult:
-		resp += fmt.Sprintf("%v", r.Total)
+		msg += fmt.Sprintf("%v", r.Total)
 		if len(r.Rolls) > 1 {
-			resp += fmt.Sprintf(" (%s)", intJoin(r.Rolls, "+"))
+			msg += fmt.Sprintf(" (%s)", intJoin(r.Rolls, "+"))
 		}
 		if len(r.Dropped) > 0 {
-			resp += fmt.Sprintf(" (dropped %s)", intJoin(r.Dropped, ", "))
+			msg += cat.Sprintf(" (dropped %s)", intJoin(r.Dropped, ", "))
 		}
 	case dice.FudgeResult:
-		resp += fmt.Sprintf("%v", r.Total)
+		msg += fmt.Sprintf("%v", r.Total)
 		if len(r.Rolls) > 1 {
-			resp += fmt.Sprintf(" (%s)", intJoin(r.Rolls, "+"))
+			msg += fmt.Sprintf(" (%s)", intJoin(r.Rolls, "+"))
 		}
 	case dice.VsResult:
-		resp += fmt.Sprintf("successes: %v", r.Successes)
+		msg += cat.Sprintf("successes: %v", r.Successes)
 		if len(r.Rolls) > 1 {
-			resp += fmt.Sprintf(" (%s)", intJoin(r.Rolls, ", "))
+			msg += fmt.Sprintf(" (%s)", intJoin(r.Rolls, ", "))
 		}
 	default:
-		resp += result.String()
+		msg += result.String()
 	}
 
-	return
+	if ctx.Sender != nil {
+		c.rolls.add(ctx.Sender.Name, msg)
+	}
+	return Reply(msg)
 }
 
-// CommandShell executes a shell script in the configured script directory
-func CommandShell(c *Client, cmd string, args ...string) (resp string) {
-	if c.Config.Mumble.Script.Directory == "" {
-		return fmt.Sprintf("%q command is not enabled", cmd)
+// CommandShell executes a shell script in the configured script directory.
+// Scripts are executed with a sanitized environment containing
+// the MUMBLE_USER and MUMBLE_CHANNEL of the sender.
+func CommandShell(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	config := &c.Config.Mumble.Script
+	if config.Directory == "" {
+		return Errorf("Error: %q command is not enabled", html.EscapeString(cmd))
 	}
 
 	if len(args) == 0 {
-		return fmt.Sprintf("Usage: %s &lt;scripts&gt; [arguments...]", cmd)
+		return Usagef("Usage: %s &lt;scripts&gt; [arguments...]", cmd)
 	}
 
-	cmd = args[0]
-	args = args[1:]
-	if strings.Contains(cmd, `/`) || strings.Contains(cmd, `\`) {
-		return fmt.Sprintf("Invalid command: %q", cmd)
+	script := args[0]
+	if strings.Contains(script, `/`) || strings.Contains(script, `\`) ||
+		(len(config.Allow) > 0 && !contains(config.Allow, script)) {
+		return Usagef("Invalid command: %q", html.EscapeString(script))
 	}
 
-	cmd = path.Join(c.Config.Mumble.Script.Directory, cmd)
-	out := new(bytes.Buffer)
-	exe := exec.Command(cmd, args...)
-	exe.Stdout = out
-	exe.Stderr = out
+	if !c.acquireScript(config.MaxParallel) {
+		return Errorf("Too many scripts running, try again later")
+	}
+	defer c.releaseScript()
 
-	if err := exe.Run(); err != nil {
-		return fmt.Sprintf("Error: %s<br/><pre>%s</pre>", err, out.String())
+	env := map[string]string{"MUMBLE_CHANNEL": ctx.ChannelName()}
+	if ctx.Sender != nil {
+		env["MUMBLE_USER"] = ctx.Sender.Name
 	}
 
-	return out.String()
+	p := &process{
+		Path:    path.Join(config.Directory, script),
+		Args:    args[1:],
+		Env:     env,
+		Timeout: config.Timeout,
+		Limit:   config.MaxOutput,
+	}
+	stdout, stderr, err := p.run()
+	out := formatOutput(stdout, stderr)
+	if err != nil {
+		return Errorf("Error: %s<br/><pre>%s</pre>", html.EscapeString(err.Error()), out)
+	}
+
+	return Reply(out)
+}
+
+// formatOutput formats the output of a process as HTML.
+func formatOutput(stdout, stderr *limitedBuffer) string {
+	out := html.EscapeString(strings.TrimRight(stdout.String()+stderr.String(), "\n"))
+	out = strings.Replace(out, "\n", "<br/>", -1)
+	if stdout.Truncated || stderr.Truncated {
+		out += "<br/><i>(output truncated)</i>"
+	}
+	return out
+}
+
+// acquireScript reserves a slot for running a script.
+// It returns false if the maximum number of running scripts has been reached.
+func (c *Client) acquireScript(max int) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.scripts >= max {
+		return false
+	}
+	c.scripts++
+	return true
+}
+
+// releaseScript releases a slot for running a script.
+func (c *Client) releaseScript() {
+	c.Lock()
+	defer c.Unlock()
+	c.scripts--
+}
+
+// CommandTranscript controls the transcription of audio and shows the transcript.
+func CommandTranscript(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if c.Config.Transcription == nil {
+		return Errorf("Error: %q command is not enabled", html.EscapeString(cmd))
+	}
+
+	n := 10
+	if len(args) == 1 {
+		switch args[0] {
+		case "start":
+			if err := c.StartTranscription(); err != nil {
+				return Errorf("Error: %s", html.EscapeString(err.Error()))
+			}
+			return Reply("Transcription started")
+		case "stop":
+			if err := c.StopTranscription(); err != nil {
+				return Errorf("Error: %s", html.EscapeString(err.Error()))
+			}
+			return Reply("Transcription stopped")
+		case "clear":
+			c.ClearTranscripts()
+			return Reply("Transcript cleared")
+		}
+
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			return Usagef("Usage: %s [start|stop|clear|&lt;lines&gt;]", cmd)
+		}
+		n = v
+	} else if len(args) > 1 {
+		return Usagef("Usage: %s [start|stop|clear|&lt;lines&gt;]", cmd)
+	}
+
+	transcripts := c.Transcripts(time.Time{})
+	if len(transcripts) == 0 {
+		return Reply("Transcript is empty")
+	}
+	if len(transcripts) > n {
+		transcripts = transcripts[len(transcripts)-n:]
+	}
+
+	cat := c.catalog(ctx)
+	lines := make([]string, len(transcripts))
+	for i, t := range transcripts {
+		lines[i] = cat.Sprintf("[%s] <b>%s</b>: %s",
+			t.Time.Format("15:04:05"), html.EscapeString(t.User), html.EscapeString(t.Text))
+	}
+	return Reply(strings.Join(lines, "<br/>"))
 }
@@ -5,15 +5,35 @@ import (
 	"gopkg.in/tucnak/telebot.v2"
 	"gopkg.in/yaml.v2"
 	"os"
+	"time"
 )
 
 const (
-	defaultCommandPrefix = "!"
+	defaultCommandPrefix        = "!"
+	defaultLanguage             = "en"
+	defaultReplyRoute           = RouteSource
+	defaultScriptTimeout        = 10 * time.Second
+	defaultScriptMaxParallel    = 2
+	defaultScriptMaxOutput      = 4096
+	defaultPluginTimeout        = 10 * time.Second
+	defaultStarlarkTimeout      = 10 * time.Second
+	defaultAuditMaxSize         = 10 << 20
+	defaultAuditMaxFiles        = 5
+	defaultTranscriptionSilence = time.Second
+	defaultTranscriptionTimeout = 30 * time.Second
+	defaultMaxTranscripts       = 1000
+	defaultLoudnessClipRatio    = 0.01
+	defaultLoudnessQuietLevel   = -40
+	defaultLoudnessWindow       = 5 * time.Second
+	defaultLoudnessWindows      = 3
+	defaultLoudnessCooldown     = 10 * time.Minute
 )
 
 // MumbleConfig represents configuration for a Mumble client.
 type MumbleConfig struct {
 	Server, User  string
+	Channel       string
+	Follow        string
 	CommandPrefix string
 	Alias         map[string]string
 	Hooks         map[string]map[string]string
@@ -21,9 +41,56 @@ type MumbleConfig struct {
 		Hold  string
 		Clips string
 	}
-	Script        struct {
+	Script struct {
+		Directory   string
+		Allow       []string
+		Timeout     time.Duration
+		MaxParallel int `yaml:"max_parallel"`
+		MaxOutput   int `yaml:"max_output"`
+	}
+	Plugins struct {
+		Directory string
+		Timeout   time.Duration
+	}
+	Starlark struct {
 		Directory string
+		Timeout   time.Duration
+	}
+	Reminders struct {
+		State string
+		Sound string
+	}
+	Initiative struct {
+		Sound string
+	}
+	Language struct {
+		Default  string
+		Catalogs string
+		State    string
 	}
+	Replies     RepliesConfig
+	Loudness    *LoudnessConfig
+	Permissions map[string]*PermissionConfig
+	RateLimits  map[string]*RateLimitConfig `yaml:"rate_limits"`
+}
+
+// RepliesConfig represents the routes of replies to commands, see the Route constants.
+// Usage is the route of usage replies, and Commands the route of replies to specific commands.
+type RepliesConfig struct {
+	Default  string
+	Usage    string
+	Commands map[string]string
+}
+
+// LoudnessConfig represents the configuration of alerts for users that are too loud or quiet.
+// ClipRatio is the fraction of clipped samples, and QuietLevel the level in dBFS,
+// that a user has to exceed for a number of consecutive windows to be notified.
+type LoudnessConfig struct {
+	ClipRatio  float64 `yaml:"clip_ratio"`
+	QuietLevel float64 `yaml:"quiet_level"`
+	Window     time.Duration
+	Windows    int
+	Cooldown   time.Duration
 }
 
 // TelegramConfig represents configuration for a Telegram client.
@@ -39,16 +106,39 @@ type MatrixConfig struct {
 }
 
 // APIConfig represents the API configuration.
+// Tokens maps the names of API tokens to their secret value.
 type APIConfig struct {
 	Address string
+	Tokens  map[string]string
+}
+
+// TranscriptionConfig represents the configuration of speech-to-text transcription.
+type TranscriptionConfig struct {
+	URL            string
+	Fields         map[string]string
+	Timeout        time.Duration
+	Silence        time.Duration
+	Enabled        bool
+	Forward        bool
+	MaxTranscripts int `yaml:"max_transcripts"`
+}
+
+// AuditConfig represents the configuration of the audit log.
+// The log is rotated when it exceeds MaxSize bytes, keeping MaxFiles rotated files.
+type AuditConfig struct {
+	File     string
+	MaxSize  int64 `yaml:"max_size"`
+	MaxFiles int   `yaml:"max_files"`
 }
 
 // Config represents configuration for a Client.
 type Config struct {
-	Mumble   *MumbleConfig
-	Matrix   *MatrixConfig
-	Telegram *TelegramConfig
-	API      *APIConfig
+	Mumble        *MumbleConfig
+	Matrix        *MatrixConfig
+	Telegram      *TelegramConfig
+	API           *APIConfig
+	Transcription *TranscriptionConfig
+	Audit         *AuditConfig
 }
 
 // LoadConfig loads a YAML configuration file.
@@ -65,6 +155,63 @@ func LoadConfig(path string) (*Config, error) {
 	if config.Mumble.CommandPrefix == "" {
 		config.Mumble.CommandPrefix = defaultCommandPrefix
 	}
+	if config.Mumble.Language.Default == "" {
+		config.Mumble.Language.Default = defaultLanguage
+	}
+	if config.Mumble.Replies.Default == "" {
+		config.Mumble.Replies.Default = defaultReplyRoute
+	}
+	if config.Mumble.Script.Timeout == 0 {
+		config.Mumble.Script.Timeout = defaultScriptTimeout
+	}
+	if config.Mumble.Script.MaxParallel == 0 {
+		config.Mumble.Script.MaxParallel = defaultScriptMaxParallel
+	}
+	if config.Mumble.Script.MaxOutput == 0 {
+		config.Mumble.Script.MaxOutput = defaultScriptMaxOutput
+	}
+	if config.Mumble.Plugins.Timeout == 0 {
+		config.Mumble.Plugins.Timeout = defaultPluginTimeout
+	}
+	if config.Mumble.Starlark.Timeout == 0 {
+		config.Mumble.Starlark.Timeout = defaultStarlarkTimeout
+	}
+	if l := config.Mumble.Loudness; l != nil {
+		if l.ClipRatio == 0 {
+			l.ClipRatio = defaultLoudnessClipRatio
+		}
+		if l.QuietLevel == 0 {
+			l.QuietLevel = defaultLoudnessQuietLevel
+		}
+		if l.Window == 0 {
+			l.Window = defaultLoudnessWindow
+		}
+		if l.Windows == 0 {
+			l.Windows = defaultLoudnessWindows
+		}
+		if l.Cooldown == 0 {
+			l.Cooldown = defaultLoudnessCooldown
+		}
+	}
+	if config.Audit != nil {
+		if config.Audit.MaxSize == 0 {
+			config.Audit.MaxSize = defaultAuditMaxSize
+		}
+		if config.Audit.MaxFiles == 0 {
+			config.Audit.MaxFiles = defaultAuditMaxFiles
+		}
+	}
+	if config.Transcription != nil {
+		if config.Transcription.Silence == 0 {
+			config.Transcription.Silence = defaultTranscriptionSilence
+		}
+		if config.Transcription.Timeout == 0 {
+			config.Transcription.Timeout = defaultTranscriptionTimeout
+		}
+		if config.Transcription.MaxTranscripts == 0 {
+			config.Transcription.MaxTranscripts = defaultMaxTranscripts
+		}
+	}
 
 	return config, nil
 }
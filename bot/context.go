@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"log"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Source is the origin of a command.
+type Source string
+
+// Sources of commands.
+const (
+	SourceInternal Source = "internal"
+	SourceMumble   Source = "mumble"
+	SourceMatrix   Source = "matrix"
+	SourceTelegram Source = "telegram"
+	SourceAPI      Source = "api"
+	SourceHook     Source = "hook"
+	SourceSchedule Source = "schedule"
+)
+
+// Routes of replies to commands.
+const (
+	// RouteSource sends replies to where the command was received from.
+	RouteSource = "source"
+
+	// RoutePrivate sends replies privately to the Mumble user that issued the command.
+	RoutePrivate = "private"
+
+	// RouteChannel sends replies to the channel of the context.
+	RouteChannel = "channel"
+
+	// RouteTree sends replies to the channel of the context and its subchannels.
+	RouteTree = "tree"
+)
+
+// ReplyTarget represents the Mumble users and channels that replies are sent to.
+type ReplyTarget struct {
+	Users    []*gumble.User
+	Channels []*gumble.Channel
+	Trees    []*gumble.Channel
+}
+
+// empty returns true if the target has no recipients.
+func (t *ReplyTarget) empty() bool {
+	return t == nil || len(t.Users)+len(t.Channels)+len(t.Trees) == 0
+}
+
+// Context represents the context a command is executed in.
+type Context struct {
+	// Sender is the issuer of the command, or nil if the command was issued by the bot itself.
+	Sender *Sender
+
+	// Channel is the Mumble channel the command applies to.
+	Channel *gumble.Channel
+
+	// Source is the origin of the command.
+	Source Source
+
+	// Reply is the target of replies sent to Mumble.
+	// Replies are sent to the Channel if it is empty.
+	Reply *ReplyTarget
+
+	// chain contains the Starlark commands and aliases that executed the command.
+	chain []string
+}
+
+// MumbleContext returns the context of a command in a Mumble text message.
+// Replies are sent to the channels the message was sent to,
+// or to the sender if it was a private message.
+func MumbleContext(msg *gumble.TextMessage) *Context {
+	ctx := &Context{
+		Sender: MumbleSender(msg.Sender),
+		Source: SourceMumble,
+		Reply:  &ReplyTarget{Channels: msg.Channels, Trees: msg.Trees},
+	}
+	if msg.Sender != nil {
+		ctx.Channel = msg.Sender.Channel
+		if ctx.Reply.empty() {
+			ctx.Reply.Users = []*gumble.User{msg.Sender}
+		}
+	}
+	return ctx
+}
+
+// NewContext returns the context of a command from the given source and sender,
+// applying to the channel of the sender if it is a Mumble user, or the channel of the bot.
+func (c *Client) NewContext(source Source, s *Sender) *Context {
+	ctx := &Context{Sender: s, Source: source}
+	if s != nil && s.User != nil && s.User.Channel != nil {
+		ctx.Channel = s.User.Channel
+	} else if c.Mumble != nil && c.Mumble.Self != nil {
+		ctx.Channel = c.Mumble.Self.Channel
+	}
+	return ctx
+}
+
+// scheduled returns a copy of the context for replies that are sent later by a timer,
+// such as the results of a poll that is closed after its timeout.
+// Replies in a scheduled context are sent to Mumble, as the linked chat is messaged separately.
+func (ctx *Context) scheduled() *Context {
+	s := *ctx
+	s.Source = SourceSchedule
+	s.chain = nil
+	return &s
+}
+
+// ChannelName returns the name of the channel of the context.
+func (ctx *Context) ChannelName() string {
+	if ctx.Channel == nil {
+		return ""
+	}
+	return ctx.Channel.Name
+}
+
+// trusted returns true if the command was issued by the bot itself.
+// Trusted commands are not subject to permissions and rate limits.
+// Hooks and scheduled commands are not trusted, as they are checked against the user that triggered them.
+func (ctx *Context) trusted() bool {
+	return ctx.Source == SourceInternal
+}
+
+// chat returns true if the command was issued from the linked Matrix or Telegram chat.
+func (ctx *Context) chat() bool {
+	return ctx.Source == SourceMatrix || ctx.Source == SourceTelegram
+}
+
+// SendReply sends the reply of a result following the route of the result,
+// or the default route if it has none. Empty replies are ignored.
+// Replies to commands from the linked chat are sent to the chat,
+// unless they are routed to a Mumble channel.
+// Replies are rendered from their parsed message, like the replies sent to the chat.
+func (c *Client) SendReply(ctx *Context, r *Result) {
+	if r == nil || r.Empty() {
+		return
+	}
+
+	route := r.route
+	if route == "" {
+		route = c.Config.Mumble.Replies.Default
+	}
+
+	r = r.localize(c.catalog(ctx))
+	if ctx.chat() && route != RouteChannel && route != RouteTree {
+		if err := c.SendMessage(r.Message()); err != nil {
+			log.Printf("Unable to send response to %s: %s", ctx.Source, err)
+		}
+		return
+	}
+
+	msg := r.Message().HTML()
+	target := c.replyTarget(ctx, route)
+	if target == nil {
+		log.Printf("Unable to send response: %s", msg)
+		return
+	}
+	c.Mumble.Send(&gumble.TextMessage{
+		Sender:   c.Mumble.Self,
+		Users:    target.Users,
+		Channels: target.Channels,
+		Trees:    target.Trees,
+		Message:  msg,
+	})
+}
+
+// replyTarget returns the recipients of a reply in a context for the given route.
+// The reply target of the context is used for the source route, or the channel of the context if it is empty.
+// Private replies can only be sent to Mumble users, nil is returned for other senders.
+func (c *Client) replyTarget(ctx *Context, route string) *ReplyTarget {
+	channel := ctx.Channel
+	if channel == nil && c.Mumble.Self != nil {
+		channel = c.Mumble.Self.Channel
+	}
+
+	switch route {
+	case RoutePrivate:
+		if ctx.Sender == nil || ctx.Sender.User == nil {
+			return nil
+		}
+		return &ReplyTarget{Users: []*gumble.User{ctx.Sender.User}}
+	case RouteTree:
+		if channel != nil {
+			return &ReplyTarget{Trees: []*gumble.Channel{channel}}
+		}
+	case RouteChannel:
+	default:
+		if !ctx.Reply.empty() {
+			return ctx.Reply
+		}
+	}
+
+	if channel == nil {
+		return nil
+	}
+	return &ReplyTarget{Channels: []*gumble.Channel{channel}}
+}
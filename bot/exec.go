@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// safeEnvironment contains the environment variables passed on to executed processes.
+var safeEnvironment = []string{"PATH", "LANG", "LC_ALL", "TZ"}
+
+// limitedBuffer is a buffer that stores a limited amount of data.
+// Data exceeding the limit is discarded.
+type limitedBuffer struct {
+	bytes.Buffer
+	Limit     int
+	Truncated bool
+}
+
+// Write writes data to the buffer, discarding any data over the limit.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if left := b.Limit - b.Len(); n > left {
+		b.Truncated = true
+		if left < 0 {
+			left = 0
+		}
+		p = p[:left]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// process describes a process to execute.
+type process struct {
+	Path    string
+	Args    []string
+	Env     map[string]string
+	Stdin   io.Reader
+	Timeout time.Duration
+	Limit   int
+}
+
+// run executes a process with a sanitized environment, and returns its output.
+// The process and all its children are killed when the timeout is exceeded.
+// Output exceeding the limit is discarded.
+func (p *process) run() (stdout, stderr *limitedBuffer, err error) {
+	stdout = &limitedBuffer{Limit: p.Limit}
+	stderr = &limitedBuffer{Limit: p.Limit}
+
+	exe := exec.Command(p.Path, p.Args...)
+	exe.Stdin = p.Stdin
+	exe.Stdout = stdout
+	exe.Stderr = stderr
+	exe.Env = make([]string, 0, len(safeEnvironment)+len(p.Env))
+	for _, k := range safeEnvironment {
+		if v, ok := os.LookupEnv(k); ok {
+			exe.Env = append(exe.Env, k+"="+v)
+		}
+	}
+	for k, v := range p.Env {
+		exe.Env = append(exe.Env, k+"="+v)
+	}
+	setProcessGroup(exe)
+
+	if err = exe.Start(); err != nil {
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- exe.Wait() }()
+
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		killProcessGroup(exe)
+		<-done
+		err = fmt.Errorf("timed out after %v", p.Timeout)
+	}
+	return
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package bot
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup configures a command to run in its own process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group of a started command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
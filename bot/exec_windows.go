@@ -0,0 +1,19 @@
+package bot
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup is not needed on Windows, as taskkill finds the children of a process.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills a started command and all its child processes.
+// The command itself is killed directly if taskkill fails.
+func killProcessGroup(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"html/template"
+	"sort"
+)
+
+var helpTemplate = `
+{{.Catalog.Translate "Available commands:"}}
+<ul>
+{{range .Commands}}
+<li><b>{{.Name}}</b>: {{$.Catalog.Translate .Summary}}</li>
+{{end}}
+</ul>
+{{if .Aliases}}
+{{.Catalog.Translate "Aliases:"}}
+<ul>
+{{range .Aliases}}
+<li><b>{{.Name}}</b>: {{.Command}}</li>
+{{end}}
+</ul>
+{{end}}
+{{.Catalog.Translate "Use help <command> to show the usage of a command."}}
+`
+
+var commandHelpTemplate = `
+{{.Catalog.Translate "Usage:"}} {{.Usage}}<br/>
+{{.Catalog.Translate .Command.Summary}}
+{{if .Command.Args}}
+<ul>
+{{range .Command.Args}}
+<li><b>{{.Name}}</b>: {{$.Catalog.Translate .Description}}</li>
+{{end}}
+</ul>
+{{end}}
+{{if .Command.Examples}}
+{{.Catalog.Translate "Examples:"}}
+<ul>
+{{range .Command.Examples}}
+<li>{{.}}</li>
+{{end}}
+</ul>
+{{end}}
+`
+
+func init() {
+	template.Must(templates.New("help").Parse(helpTemplate))
+	template.Must(templates.New("commandHelp").Parse(commandHelpTemplate))
+}
+
+// helpEntry is a single entry in the list of commands and aliases.
+type helpEntry struct {
+	Name, Summary, Command string
+}
+
+// CommandHelp lists the available commands, or shows the usage of a command.
+func CommandHelp(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) > 1 {
+		return Usagef("Usage: %s [command]", cmd)
+	}
+
+	if len(args) == 1 {
+		return renderCommandHelp(c, c.catalog(ctx), args[0])
+	}
+
+	commands := c.Commands()
+	params := struct {
+		Commands, Aliases []helpEntry
+		Catalog           Catalog
+	}{
+		Catalog:  c.catalog(ctx),
+		Commands: make([]helpEntry, 0, len(commands)),
+		Aliases:  make([]helpEntry, 0, len(c.Config.Mumble.Alias)),
+	}
+	for name, command := range commands {
+		params.Commands = append(params.Commands, helpEntry{Name: name, Summary: command.Summary})
+	}
+	for name, alias := range c.Config.Mumble.Alias {
+		if _, ok := commands[name]; !ok {
+			params.Aliases = append(params.Aliases, helpEntry{Name: name, Command: alias})
+		}
+	}
+	sortHelpEntries(params.Commands)
+	sortHelpEntries(params.Aliases)
+
+	help, err := renderTemplate("help", params)
+	if err != nil {
+		return Errorf("Error: %s", template.HTMLEscapeString(err.Error()))
+	}
+	return Reply(help)
+}
+
+// renderCommandHelp renders the usage of a single command or alias, translated using a catalog.
+func renderCommandHelp(c *Client, cat Catalog, name string) *Result {
+	command := c.Command(name)
+	if command == nil {
+		if alias, ok := c.Config.Mumble.Alias[name]; ok {
+			return Replyf("<b>%s</b> is an alias for: %s", template.HTMLEscapeString(name), template.HTMLEscapeString(alias))
+		}
+		return newResult(StatusUnknown, "Unknown command: %s", template.HTMLEscapeString(name))
+	}
+
+	params := struct {
+		Usage   string
+		Command *Command
+		Catalog Catalog
+	}{
+		Usage:   command.Usage(name),
+		Command: command,
+		Catalog: cat,
+	}
+	help, err := renderTemplate("commandHelp", params)
+	if err != nil {
+		return Errorf("Error: %s", template.HTMLEscapeString(err.Error()))
+	}
+	return Reply(help)
+}
+
+// sortHelpEntries sorts help entries by name.
+func sortHelpEntries(entries []helpEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justinian/dice"
+)
+
+// combatant represents a participant in the initiative order.
+type combatant struct {
+	Name  string
+	Dice  string
+	Roll  int
+	order int
+}
+
+// initiative tracks the initiative order and turns of an encounter.
+type initiative struct {
+	sync.Mutex
+	combatants []*combatant
+	turn       int
+	round      int
+	added      int
+}
+
+// add rolls the initiative for a combatant, and adds it to the initiative order.
+// Combatants with equal rolls keep the order in which they were added.
+// A combatant with the same name is replaced.
+func (t *initiative) add(name, description string) (*combatant, error) {
+	result, _, err := dice.Roll(description)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	// Keep the turn and round on the current combatant,
+	// which is the new entry if the current combatant is added again.
+	var current string
+	if t.round > 0 && t.turn >= 0 && t.turn < len(t.combatants) {
+		current = t.combatants[t.turn].Name
+	}
+	round := t.round
+	t.remove(name)
+	t.round = round
+
+	e := &combatant{Name: name, Dice: description, Roll: result.Int(), order: t.added}
+	t.added++
+
+	t.combatants = append(t.combatants, e)
+	sort.SliceStable(t.combatants, func(i, j int) bool {
+		if t.combatants[i].Roll == t.combatants[j].Roll {
+			return t.combatants[i].order < t.combatants[j].order
+		}
+		return t.combatants[i].Roll > t.combatants[j].Roll
+	})
+
+	if current != "" {
+		t.turn = t.index(current)
+	}
+	return e, nil
+}
+
+// remove removes a combatant from the initiative order,
+// and returns false if it does not exist.
+// Removing the current combatant moves the turn back,
+// so that the next turn is of the combatant following it.
+// It must be called with the lock held.
+func (t *initiative) remove(name string) bool {
+	i := t.index(name)
+	if i < 0 {
+		return false
+	}
+
+	t.combatants = append(t.combatants[:i], t.combatants[i+1:]...)
+	switch {
+	case len(t.combatants) == 0:
+		t.turn, t.round = 0, 0
+	case i < t.turn, i == t.turn && t.round > 0:
+		t.turn--
+	}
+	return true
+}
+
+// index returns the index of a combatant in the initiative order, or -1.
+// It must be called with the lock held.
+func (t *initiative) index(name string) int {
+	for i, e := range t.combatants {
+		if strings.EqualFold(e.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// next advances the turn, and returns the combatant whose turn it is and the round.
+func (t *initiative) next() (*combatant, int) {
+	t.Lock()
+	defer t.Unlock()
+
+	if len(t.combatants) == 0 {
+		return nil, 0
+	}
+
+	if t.round == 0 {
+		t.turn, t.round = 0, 1
+	} else if t.turn++; t.turn >= len(t.combatants) {
+		t.turn = 0
+		t.round++
+	}
+	return t.combatants[t.turn], t.round
+}
+
+// html returns the initiative order as HTML, translated using the given catalog.
+func (t *initiative) html(cat Catalog) string {
+	t.Lock()
+	defer t.Unlock()
+
+	if len(t.combatants) == 0 {
+		return cat.Translate("The initiative order is empty")
+	}
+
+	lines := make([]string, len(t.combatants))
+	for i, e := range t.combatants {
+		lines[i] = fmt.Sprintf("%s: %v (%s)", html.EscapeString(e.Name), e.Roll, html.EscapeString(e.Dice))
+		if t.round > 0 && i == t.turn {
+			lines[i] = "<b>" + lines[i] + "</b>"
+		}
+		lines[i] = "<li>" + lines[i] + "</li>"
+	}
+
+	if t.round > 0 {
+		return cat.Sprintf("Initiative order, round %v:<ol>%s</ol>", t.round, strings.Join(lines, ""))
+	}
+	return cat.Sprintf("Initiative order:<ol>%s</ol>", strings.Join(lines, ""))
+}
+
+// initiative returns the initiative tracker of a channel.
+func (c *Client) initiative(channel string) *initiative {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.initiatives == nil {
+		c.initiatives = make(map[string]*initiative)
+	}
+	t, ok := c.initiatives[channel]
+	if !ok {
+		t = new(initiative)
+		c.initiatives[channel] = t
+	}
+	return t
+}
+
+// CommandInitiative tracks the initiative order and turns in the channel of the context.
+func CommandInitiative(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	usage := Usagef("Usage: %s add &lt;name&gt; &lt;dice&gt; | remove &lt;name&gt; | next | list | clear", cmd)
+	if len(args) == 0 {
+		return usage
+	}
+
+	channel := ctx.ChannelName()
+	t := c.initiative(channel)
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			return Usagef("Usage: %s add &lt;name&gt; &lt;dice&gt;<br/>Example: %s add Goblin 1d20+2", cmd, cmd)
+		}
+
+		e, err := t.add(args[1], strings.Join(args[2:], " "))
+		if err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+		return Replyf("%s rolled %v for initiative", html.EscapeString(e.Name), e.Roll)
+	case "remove":
+		if len(args) != 2 {
+			return Usagef("Usage: %s remove &lt;name&gt;", cmd)
+		}
+
+		t.Lock()
+		ok := t.remove(args[1])
+		t.Unlock()
+		if !ok {
+			return Errorf("Error: %q is not in the initiative order", html.EscapeString(args[1]))
+		}
+		return Replyf("Removed %s from the initiative order", html.EscapeString(args[1]))
+	case "next":
+		e, round := t.next()
+		if e == nil {
+			return Replyf("The initiative order is empty, add combatants using: %s add &lt;name&gt; &lt;dice&gt;", cmd)
+		}
+
+		c.PlayNotification(c.Config.Mumble.Initiative.Sound)
+		return Replyf("Round %v: it is the turn of <b>%s</b> (%v)", round, html.EscapeString(e.Name), e.Roll)
+	case "list":
+		return Reply(t.html(c.catalog(ctx)))
+	case "clear":
+		c.Lock()
+		delete(c.initiatives, channel)
+		c.Unlock()
+		return Reply("Cleared the initiative order")
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,62 @@
+package bot
+
+import "testing"
+
+// newTestInitiative returns an initiative order of combatants with descending rolls.
+func newTestInitiative(names ...string) *initiative {
+	t := new(initiative)
+	for i, name := range names {
+		t.combatants = append(t.combatants, &combatant{Name: name, Dice: "1d20", Roll: 20 - i, order: i})
+	}
+	t.added = len(names)
+	return t
+}
+
+func TestInitiativeRemove(t *testing.T) {
+	tests := []struct {
+		name   string
+		turns  int
+		remove string
+		next   string
+		round  int
+	}{
+		{"before start", 0, "a", "b", 1},
+		{"before current", 2, "a", "c", 1},
+		{"after current", 2, "c", "a", 2},
+		{"current", 2, "b", "c", 1},
+		{"current first", 1, "a", "b", 1},
+		{"current last", 3, "c", "a", 2},
+	}
+
+	for _, tt := range tests {
+		order := newTestInitiative("a", "b", "c")
+		for i := 0; i < tt.turns; i++ {
+			order.next()
+		}
+
+		order.Lock()
+		ok := order.remove(tt.remove)
+		order.Unlock()
+		if !ok {
+			t.Errorf("%s: remove(%q) = false, expected true", tt.name, tt.remove)
+			continue
+		}
+
+		order.html(nil)
+		if e, round := order.next(); e == nil || e.Name != tt.next || round != tt.round {
+			t.Errorf("%s: next = %v in round %v, expected %s in round %v", tt.name, e, round, tt.next, tt.round)
+		}
+	}
+}
+
+func TestInitiativeRemoveAll(t *testing.T) {
+	order := newTestInitiative("a")
+	order.next()
+
+	order.Lock()
+	order.remove("a")
+	order.Unlock()
+	if e, round := order.next(); e != nil || round != 0 {
+		t.Errorf("next = %v in round %v, expected no combatant", e, round)
+	}
+}
@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CatalogExtension contains the filename extension of message catalogs.
+const CatalogExtension = ".yaml"
+
+// Catalog contains the translations of the messages of the bot in a language.
+// Messages are keyed by their English format string, for example:
+//
+//	"Volume set to %+v dB": "Volume ingesteld op %+v dB"
+type Catalog map[string]string
+
+// Translate returns the translation of a message,
+// or the message itself if it has no translation.
+func (cat Catalog) Translate(message string) string {
+	if t, ok := cat[message]; ok {
+		return t
+	}
+	return message
+}
+
+// Sprintf formats the translation of a format string.
+func (cat Catalog) Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(cat.Translate(format), a...)
+}
+
+// languages contains the language preferences of users, keyed by their identity.
+type languages struct {
+	sync.Mutex
+	file  string
+	users map[string]string
+}
+
+// newLanguages returns the language preferences persisted in the given file.
+func newLanguages(file string) (*languages, error) {
+	l := &languages{file: file, users: make(map[string]string)}
+	if file == "" {
+		return l, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	return l, json.Unmarshal(data, &l.users)
+}
+
+// get returns the preferred language of a user, if any.
+func (l *languages) get(user string) string {
+	l.Lock()
+	defer l.Unlock()
+	return l.users[user]
+}
+
+// set sets the preferred language of a user, or removes it if the language is empty.
+func (l *languages) set(user, language string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if language == "" {
+		delete(l.users, user)
+	} else {
+		l.users[user] = language
+	}
+	l.save()
+}
+
+// save writes the language preferences to the state file.
+// It must be called with the lock held.
+func (l *languages) save() {
+	if l.file == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(l.users, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding language preferences: %s", err)
+		return
+	}
+
+	tmp := l.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+		log.Printf("Error saving language preferences: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, l.file); err != nil {
+		log.Printf("Error saving language preferences: %s", err)
+	}
+}
+
+// LoadCatalogs (re)loads the message catalogs in the configured directory.
+// The name of a catalog file, without extension, is the language it contains.
+// The previously loaded catalogs are kept if any catalog fails to load.
+func (c *Client) LoadCatalogs() error {
+	catalogs := make(map[string]Catalog)
+	if dir := c.Config.Mumble.Language.Catalogs; dir != "" {
+		if err := loadCatalogs(dir, catalogs); err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.catalogs = catalogs
+	return nil
+}
+
+// loadCatalogs loads the message catalogs in a directory into a map.
+func loadCatalogs(dir string, catalogs map[string]Catalog) error {
+	files, err := listFiles(dir, CatalogExtension)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name+CatalogExtension))
+		if err != nil {
+			return err
+		}
+		var cat Catalog
+		if err := yaml.Unmarshal(data, &cat); err != nil {
+			return fmt.Errorf("parsing catalog %q: %w", name, err)
+		}
+		catalogs[name] = cat
+	}
+	return nil
+}
+
+// loadLanguages loads the persisted language preferences of users.
+func (c *Client) loadLanguages() (err error) {
+	c.languages, err = newLanguages(c.Config.Mumble.Language.State)
+	return
+}
+
+// userLanguage returns the language of the user with the given identity,
+// which is the preferred language of the user, or the default language.
+func (c *Client) userLanguage(user string) string {
+	if user != "" && c.languages != nil {
+		if l := c.languages.get(user); l != "" {
+			return l
+		}
+	}
+	return c.Config.Mumble.Language.Default
+}
+
+// userCatalog returns the message catalog for the user with the given identity.
+// The catalog is empty if the language has no catalog.
+func (c *Client) userCatalog(user string) Catalog {
+	language := c.userLanguage(user)
+
+	c.Lock()
+	defer c.Unlock()
+	return c.catalogs[language]
+}
+
+// catalog returns the message catalog for the sender of a context.
+func (c *Client) catalog(ctx *Context) Catalog {
+	return c.userCatalog(senderIdentity(ctx.Sender))
+}
+
+// availableLanguages returns the default language and the languages with a catalog.
+func (c *Client) availableLanguages() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	list := []string{c.Config.Mumble.Language.Default}
+	for l := range c.catalogs {
+		if l != c.Config.Mumble.Language.Default {
+			list = append(list, l)
+		}
+	}
+	sort.Strings(list[1:])
+	return list
+}
+
+// CommandLanguage shows or sets the preferred language of the sender.
+func CommandLanguage(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	user := senderIdentity(ctx.Sender)
+	available := c.availableLanguages()
+
+	switch {
+	case len(args) == 0:
+		return Replyf("Your language is %s. Available languages: %s",
+			html.EscapeString(c.userLanguage(user)), html.EscapeString(strings.Join(available, ", ")))
+	case len(args) > 1:
+		return Usagef("Usage: %s [language|default]", cmd)
+	case user == "":
+		return Errorf("Error: unable to store a language without a sender")
+	case args[0] == defaultSubject:
+		c.languages.set(user, "")
+		return Replyf("Your language is reset to %s", html.EscapeString(c.userLanguage(user)))
+	}
+
+	for _, l := range available {
+		if l == args[0] {
+			c.languages.set(user, l)
+			return Replyf("Your language is set to %s", html.EscapeString(l))
+		}
+	}
+	return Errorf("Error: unknown language %q, available languages: %s",
+		html.EscapeString(args[0]), html.EscapeString(strings.Join(available, ", ")))
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// clipLevel is the absolute sample value from which a sample is considered clipped.
+const clipLevel = 32439
+
+// loudnessStats contains the audio statistics of a single user.
+type loudnessStats struct {
+	samples, clipped int
+	squares          float64
+	loud, quiet      int
+	notified         time.Time
+}
+
+// loudness measures the audio level of every user, and notifies users
+// that are consistently clipping or far too quiet.
+// It implements mumble.AudioHandler.
+type loudness struct {
+	sync.Mutex
+	client *Client
+	stats  map[uint32]*loudnessStats
+}
+
+// newLoudness returns a new loudness monitor.
+func newLoudness(c *Client) *loudness {
+	return &loudness{
+		client: c,
+		stats:  make(map[uint32]*loudnessStats),
+	}
+}
+
+// HandleAudio measures the audio level of a user.
+func (l *loudness) HandleAudio(user *gumble.User, pcm []int16) {
+	config := l.client.Config.Mumble.Loudness
+	if config == nil || silent(pcm) {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	s, ok := l.stats[user.Session]
+	if !ok {
+		s = new(loudnessStats)
+		l.stats[user.Session] = s
+	}
+
+	for _, v := range pcm {
+		if v >= clipLevel || v <= -clipLevel {
+			s.clipped++
+		}
+		s.squares += float64(v) * float64(v)
+	}
+	s.samples += len(pcm)
+
+	if s.samples < int(config.Window*gumble.AudioSampleRate/time.Second) {
+		return
+	}
+
+	clipped := float64(s.clipped) / float64(s.samples)
+	level := 20 * math.Log10(math.Sqrt(s.squares/float64(s.samples))/32768)
+	s.samples, s.clipped, s.squares = 0, 0, 0
+
+	switch {
+	case clipped >= config.ClipRatio:
+		s.loud++
+		s.quiet = 0
+	case level <= config.QuietLevel:
+		s.quiet++
+		s.loud = 0
+	default:
+		s.loud, s.quiet = 0, 0
+		return
+	}
+
+	if time.Since(s.notified) < config.Cooldown {
+		return
+	}
+
+	switch {
+	case s.loud >= config.Windows:
+		s.loud, s.notified = 0, time.Now()
+		go l.notifyLoud(user, clipped)
+	case s.quiet >= config.Windows:
+		s.quiet, s.notified = 0, time.Now()
+		go l.notifyQuiet(user, level)
+	}
+}
+
+// remove removes the statistics of a user that disconnected.
+func (l *loudness) remove(session uint32) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.stats, session)
+}
+
+// notifyLoud notifies a user that their audio is clipping.
+func (l *loudness) notifyLoud(user *gumble.User, clipped float64) {
+	log.Printf("User %q is clipping (%.1f%% of samples)", user.Name, 100*clipped)
+	cat := l.client.userCatalog(senderIdentity(MumbleSender(user)))
+	user.Send(cat.Sprintf("Your audio is clipping (%.1f%% of samples). "+
+		"Please lower your microphone volume.", 100*clipped))
+	l.client.ExecuteHook(loudUserHook, l.client.NewContext(SourceHook, MumbleSender(user)))
+}
+
+// notifyQuiet notifies a user that their audio is too quiet.
+func (l *loudness) notifyQuiet(user *gumble.User, level float64) {
+	log.Printf("User %q is too quiet (%.1f dBFS)", user.Name, level)
+	cat := l.client.userCatalog(senderIdentity(MumbleSender(user)))
+	user.Send(cat.Sprintf("Your audio is very quiet (%.1f dBFS). "+
+		"Please raise your microphone volume.", level))
+}
+
+// silent returns true if a frame of audio contains only silence.
+func silent(pcm []int16) bool {
+	for _, v := range pcm {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
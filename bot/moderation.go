@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Permission subjects for moderation commands.
+// Both enabling and disabling muting or deafening use the same subject.
+const (
+	muteSubject   = "mute"
+	deafenSubject = "deafen"
+	kickSubject   = "kick"
+	banSubject    = "ban"
+)
+
+// checkChannelPermission returns an error if the bot is known to lack a permission in a channel.
+func checkChannelPermission(channel *gumble.Channel, p gumble.Permission, action string) error {
+	if perm := channel.Permission(); perm != nil && !perm.Has(p) {
+		return fmt.Errorf("not allowed to %s %q", action, channel.Name)
+	}
+	return nil
+}
+
+// moderationTarget returns the connected user with the given name,
+// or an error result if the user does not exist or is the bot itself.
+func (c *Client) moderationTarget(name string) (*gumble.User, *Result) {
+	u := c.Mumble.FindUser(name)
+	switch {
+	case u == nil:
+		return nil, Errorf("Error: unknown user %q", html.EscapeString(name))
+	case u == c.Mumble.Self:
+		return nil, Errorf("Error: the bot can not moderate itself")
+	}
+	return u, nil
+}
+
+// moderated logs a moderation action, reports it to the linked chat, and returns the reply.
+// Actions issued from the chat are not reported, as the reply is already sent to the chat.
+func (c *Client) moderated(ctx *Context, format string, a ...interface{}) *Result {
+	resp := Replyf(format, a...)
+	log.Printf("Moderation by %s: %s", moderatorName(c, ctx), resp.PlainText())
+	if !ctx.chat() {
+		if err := c.SendMessage(resp.localize(c.userCatalog("")).Message()); err != nil {
+			log.Printf("Error reporting moderation: %s", err)
+		}
+	}
+	return resp
+}
+
+// moderatorName returns the name of the sender of a context, or the name of the bot.
+func moderatorName(c *Client, ctx *Context) string {
+	if ctx.Sender == nil {
+		return c.Config.Mumble.User
+	}
+	return ctx.Sender.Name
+}
+
+// muteHandler returns a handler that mutes or unmutes a user.
+func muteHandler(muted bool) CommandHandler {
+	return func(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+		if len(args) != 1 {
+			return Usagef("Usage: %s &lt;user&gt;", cmd)
+		}
+		if err := c.checkPermission(ctx, muteSubject); err != nil {
+			return newResult(StatusDenied, "Permission denied: %s", html.EscapeString(err.Error()))
+		}
+
+		u, resp := c.moderationTarget(args[0])
+		if resp != nil {
+			return resp
+		}
+		if err := checkChannelPermission(u.Channel, gumble.PermissionMuteDeafen, "mute users in"); err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+
+		u.SetMuted(muted)
+		if muted {
+			return c.moderated(ctx, "%s muted %s", html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name))
+		}
+		return c.moderated(ctx, "%s unmuted %s", html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name))
+	}
+}
+
+// deafenHandler returns a handler that deafens or undeafens a user.
+func deafenHandler(deafened bool) CommandHandler {
+	return func(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+		if len(args) != 1 {
+			return Usagef("Usage: %s &lt;user&gt;", cmd)
+		}
+		if err := c.checkPermission(ctx, deafenSubject); err != nil {
+			return newResult(StatusDenied, "Permission denied: %s", html.EscapeString(err.Error()))
+		}
+
+		u, resp := c.moderationTarget(args[0])
+		if resp != nil {
+			return resp
+		}
+		if err := checkChannelPermission(u.Channel, gumble.PermissionMuteDeafen, "deafen users in"); err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+
+		u.SetDeafened(deafened)
+		if deafened {
+			return c.moderated(ctx, "%s deafened %s", html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name))
+		}
+		return c.moderated(ctx, "%s undeafened %s", html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name))
+	}
+}
+
+// CommandMove moves a user into a channel.
+func CommandMove(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) != 2 {
+		return Usagef("Usage: %s &lt;user&gt; &lt;channel&gt;", cmd)
+	}
+
+	u, resp := c.moderationTarget(args[0])
+	if resp != nil {
+		return resp
+	}
+	channel := c.Mumble.FindChannel(args[1])
+	if channel == nil {
+		return Errorf("Error: unknown channel %q", html.EscapeString(args[1]))
+	}
+	if err := checkMove(channel); err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+
+	u.Move(channel)
+	return c.moderated(ctx, "%s moved %s to %s",
+		html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name), html.EscapeString(channel.Name))
+}
+
+// CommandKick kicks a user from the server.
+func CommandKick(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	return c.removeUser(ctx, cmd, args, false)
+}
+
+// CommandBan bans a user from the server.
+func CommandBan(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	return c.removeUser(ctx, cmd, args, true)
+}
+
+// removeUser kicks or bans the user named in the arguments, with an optional reason.
+func (c *Client) removeUser(ctx *Context, cmd string, args []string, ban bool) *Result {
+	if len(args) < 1 {
+		return Usagef("Usage: %s &lt;user&gt; [reason]", cmd)
+	}
+
+	u, resp := c.moderationTarget(args[0])
+	if resp != nil {
+		return resp
+	}
+	reason := strings.Join(args[1:], " ")
+
+	perm, action, format := gumble.PermissionKick, "kick users from", "%s kicked %s"
+	if ban {
+		perm, action, format = gumble.PermissionBan, "ban users from", "%s banned %s"
+	}
+	if root := c.Mumble.Channels[0]; root != nil {
+		if err := checkChannelPermission(root, perm, action); err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+	}
+
+	if ban {
+		u.Ban(reason)
+	} else {
+		u.Kick(reason)
+	}
+
+	if reason == "" {
+		return c.moderated(ctx, format, html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name))
+	}
+	return c.moderated(ctx, format+": %s",
+		html.EscapeString(moderatorName(c, ctx)), html.EscapeString(u.Name), html.EscapeString(reason))
+}
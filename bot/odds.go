@@ -0,0 +1,371 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/justinian/dice"
+)
+
+const (
+	// maxOddsOperations is the maximum number of operations to compute a distribution.
+	maxOddsOperations = 1e8
+
+	// minOddsProbability is the probability below which the tail of an unbounded distribution is ignored.
+	minOddsProbability = 1e-12
+)
+
+// distribution represents the probability distribution of the integer outcome of a roll.
+// P[i] contains the probability of the outcome Min+i.
+type distribution struct {
+	Min int
+	P   []float64
+}
+
+// uniform returns the distribution of a single die with values from min to max.
+func uniform(min, max int) *distribution {
+	d := &distribution{Min: min, P: make([]float64, max-min+1)}
+	for i := range d.P {
+		d.P[i] = 1 / float64(len(d.P))
+	}
+	return d
+}
+
+// add returns the distribution of the sum of two independent outcomes.
+func (d *distribution) add(o *distribution) *distribution {
+	r := &distribution{Min: d.Min + o.Min, P: make([]float64, len(d.P)+len(o.P)-1)}
+	for i, p := range d.P {
+		for j, q := range o.P {
+			r.P[i+j] += p * q
+		}
+	}
+	return r
+}
+
+// repeat returns the distribution of the sum of n independent outcomes.
+func (d *distribution) repeat(n int) *distribution {
+	r := &distribution{P: []float64{1}}
+	for i := 0; i < n; i++ {
+		r = r.add(d)
+	}
+	return r
+}
+
+// mean returns the expected value of the distribution.
+func (d *distribution) mean() (m float64) {
+	for i, p := range d.P {
+		m += float64(d.Min+i) * p
+	}
+	return
+}
+
+// stddev returns the standard deviation of the distribution.
+func (d *distribution) stddev() float64 {
+	m := d.mean()
+	v := 0.0
+	for i, p := range d.P {
+		v += (float64(d.Min+i) - m) * (float64(d.Min+i) - m) * p
+	}
+	return math.Sqrt(v)
+}
+
+// probability returns the probability of an outcome that matches the given comparison.
+func (d *distribution) probability(op string, target int) (sum float64) {
+	for i, p := range d.P {
+		v := d.Min + i
+		switch {
+		case op == ">=" && v >= target,
+			op == ">" && v > target,
+			op == "<=" && v <= target,
+			op == "<" && v < target,
+			op == "=" && v == target:
+			sum += p
+		}
+	}
+	return
+}
+
+// trim removes the impossible outcomes from both ends of the distribution.
+func (d *distribution) trim() *distribution {
+	for len(d.P) > 1 && d.P[0] == 0 {
+		d.P = d.P[1:]
+		d.Min++
+	}
+	for len(d.P) > 1 && d.P[len(d.P)-1] == 0 {
+		d.P = d.P[:len(d.P)-1]
+	}
+	return d
+}
+
+// max returns the maximum possible outcome.
+func (d *distribution) max() int {
+	return d.Min + len(d.P) - 1
+}
+
+// parseOdds computes the distribution of the outcome of a dice description,
+// using the syntax of dice.Roll.
+func parseOdds(desc string) (*distribution, error) {
+	if (dice.EoteRoller{}).Pattern().MatchString(desc) {
+		return nil, fmt.Errorf("odds of Edge of the Empire dice are not supported")
+	}
+	if m := (dice.FudgeRoller{}).Pattern().FindStringSubmatch(desc); m != nil {
+		return fudgeOdds(m)
+	}
+	if m := (dice.StdRoller{}).Pattern().FindStringSubmatch(desc); m != nil {
+		return stdOdds(m)
+	}
+	if m := (dice.VsRoller{}).Pattern().FindStringSubmatch(desc); m != nil {
+		return vsOdds(m)
+	}
+	return nil, fmt.Errorf("bad roll format: %s", desc)
+}
+
+// atoi converts the matches of a dice pattern to integers.
+// Empty matches are converted to zero.
+func atoi(matches ...string) ([]int, error) {
+	r := make([]int, len(matches))
+	for i, s := range matches {
+		if s == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		r[i] = v
+	}
+	return r, nil
+}
+
+// checkOperations returns an error if computing a distribution takes too many operations.
+func checkOperations(n float64) error {
+	if n > maxOddsOperations {
+		return fmt.Errorf("too many dice to compute the odds")
+	}
+	return nil
+}
+
+// fudgeOdds returns the distribution of fudge dice with an optional bonus.
+func fudgeOdds(m []string) (*distribution, error) {
+	v, err := atoi(m[1], m[2])
+	if err != nil {
+		return nil, err
+	}
+	n, bonus := v[0], v[1]
+	if err := checkOperations(float64(n) * float64(n) * 3); err != nil {
+		return nil, err
+	}
+
+	d := uniform(-1, 1).repeat(n)
+	d.Min += bonus
+	return d, nil
+}
+
+// stdOdds returns the distribution of standard dice, with optional keep/drop and bonus.
+func stdOdds(m []string) (*distribution, error) {
+	v, err := atoi(m[1], m[2], m[5], m[6])
+	if err != nil {
+		return nil, err
+	}
+	n, sides, num, bonus := v[0], v[1], v[2], v[3]
+	if sides <= 0 {
+		return nil, fmt.Errorf("sides must be 1 or more")
+	}
+	if num > n {
+		return nil, fmt.Errorf("cannot keep or drop %v of %v dice", num, n)
+	}
+
+	// Convert the keep/drop to a number of highest or lowest dice to keep
+	keep, highest := n, true
+	switch m[4] {
+	case "k", "kh":
+		keep = num
+	case "d", "dl":
+		keep = n - num
+	case "kl":
+		keep, highest = num, false
+	case "dh":
+		keep, highest = n-num, false
+	}
+
+	var d *distribution
+	if keep == n {
+		if err := checkOperations(float64(n) * float64(n) * float64(sides) * float64(sides)); err != nil {
+			return nil, err
+		}
+		d = uniform(1, sides).repeat(n)
+	} else {
+		if err := checkOperations(float64(sides) * float64(n) * float64(n) * float64(keep*sides+1)); err != nil {
+			return nil, err
+		}
+		d = keepOdds(n, sides, keep, highest)
+	}
+
+	d.Min += bonus
+	return d, nil
+}
+
+// keepOdds returns the distribution of the sum of the highest or lowest dice that are kept.
+// The faces are processed from the preferred end, distributing the dice over them,
+// so that the first dice to be assigned are the ones that are kept.
+func keepOdds(n, sides, keep int, highest bool) *distribution {
+	// p[used][sum] contains the probability of having assigned a number of dice
+	// to the processed faces, with the given sum of kept dice.
+	p := make([][]float64, n+1)
+	for i := range p {
+		p[i] = make([]float64, keep*sides+1)
+	}
+	p[0][0] = 1
+
+	for f := 1; f <= sides; f++ {
+		face := f
+		if highest {
+			face = sides - f + 1
+		}
+
+		next := make([][]float64, n+1)
+		for i := range next {
+			next[i] = make([]float64, keep*sides+1)
+		}
+		for used := 0; used <= n; used++ {
+			for sum, q := range p[used] {
+				if q == 0 {
+					continue
+				}
+
+				// Assign c of the remaining dice to this face, each with probability 1/sides
+				for c := 0; c <= n-used; c++ {
+					w := binomial(n-used, c) * math.Pow(1/float64(sides), float64(c))
+					kept := minInt(used+c, keep) - minInt(used, keep)
+					next[used+c][sum+kept*face] += q * w
+				}
+			}
+		}
+		p = next
+	}
+
+	return (&distribution{P: p[n]}).trim()
+}
+
+// vsOdds returns the distribution of the number of successes of dice versus a target,
+// with optional exploding or rerolling of the maximum value.
+func vsOdds(m []string) (*distribution, error) {
+	v, err := atoi(m[1], m[2], m[4])
+	if err != nil {
+		return nil, err
+	}
+	n, sides, target := v[0], v[1], v[2]
+	if n < 1 {
+		return nil, fmt.Errorf("count must be 1 or more")
+	}
+	if sides < 2 {
+		return nil, fmt.Errorf("sides must be 2 or more")
+	}
+
+	s := float64(sides)
+	var die *distribution
+	switch m[3] {
+	case "e":
+		// An exploding die is a single success if its total reaches the target
+		success, chance := 0.0, 1/s
+		for k := 0; chance > minOddsProbability; k++ {
+			if k*sides+1 >= target {
+				success += chance * s
+				break
+			}
+			for x := 1; x < sides; x++ {
+				if k*sides+x >= target {
+					success += chance
+				}
+			}
+			chance /= s
+		}
+		die = &distribution{P: []float64{1 - success, success}}
+	case "r":
+		// A die that rolls the maximum value is rolled again,
+		// and every roll that reaches the target is a success
+		max := sides >= target
+		die = &distribution{P: []float64{0}}
+		for k, chance := 0, 1/s; chance > minOddsProbability; k, chance = k+1, chance/s {
+			for x := 1; x < sides; x++ {
+				successes := 0
+				if max {
+					successes = k
+				}
+				if x >= target {
+					successes++
+				}
+				for len(die.P) <= successes {
+					die.P = append(die.P, 0)
+				}
+				die.P[successes] += chance
+			}
+		}
+	default:
+		success := math.Max(0, math.Min(1, float64(sides-target+1)/s))
+		die = &distribution{P: []float64{1 - success, success}}
+	}
+
+	if err := checkOperations(float64(n) * float64(n) * float64(len(die.P)) * float64(len(die.P))); err != nil {
+		return nil, err
+	}
+	return die.repeat(n), nil
+}
+
+// binomial returns the binomial coefficient n choose k.
+func binomial(n, k int) float64 {
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r *= float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+// minInt returns the smallest of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseTarget parses a target like `>=15`, or a comparison and a number.
+func parseTarget(args []string) (op string, target int, err error) {
+	s := strings.Join(args, "")
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			target, err = strconv.Atoi(strings.TrimPrefix(s, op))
+			return op, target, err
+		}
+	}
+	return "", 0, fmt.Errorf("invalid target %q", s)
+}
+
+// CommandOdds shows the odds of a dice roll.
+func CommandOdds(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) == 0 {
+		return Usagef("Usage: %s &lt;description&gt; [&gt;=target]<br/>Example: %s 4d6kh3 &gt;=15", cmd, cmd)
+	}
+
+	d, err := parseOdds(args[0])
+	if err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+
+	if len(args) == 1 {
+		return Replyf("Odds of %s: mean %.2f, standard deviation %.2f, range %v to %v",
+			html.EscapeString(args[0]), d.mean(), d.stddev(), d.Min, d.max())
+	}
+
+	op, target, err := parseTarget(args[1:])
+	if err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	return Replyf("Odds of %s: mean %.2f, standard deviation %.2f, range %v to %v<br/>Chance of %s %v: %.2f%%",
+		html.EscapeString(args[0]), d.mean(), d.stddev(), d.Min, d.max(),
+		html.EscapeString(op), target, 100*d.probability(op, target))
+}
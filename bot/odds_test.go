@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// oddsEpsilon is the tolerance when comparing probabilities.
+const oddsEpsilon = 1e-9
+
+// bruteKeepOdds computes the distribution of keeping dice by enumerating all rolls.
+func bruteKeepOdds(n, sides, keep int, highest bool) map[int]float64 {
+	odds := make(map[int]float64)
+	rolls := make([]int, n)
+	total := math.Pow(float64(sides), float64(n))
+
+	var roll func(i int)
+	roll = func(i int) {
+		if i == n {
+			sorted := append([]int(nil), rolls...)
+			sort.Ints(sorted)
+			if highest {
+				sorted = sorted[n-keep:]
+			} else {
+				sorted = sorted[:keep]
+			}
+			sum := 0
+			for _, v := range sorted {
+				sum += v
+			}
+			odds[sum] += 1 / total
+			return
+		}
+		for v := 1; v <= sides; v++ {
+			rolls[i] = v
+			roll(i + 1)
+		}
+	}
+	roll(0)
+	return odds
+}
+
+func TestKeepOdds(t *testing.T) {
+	tests := []struct {
+		n, sides, keep int
+		highest        bool
+	}{
+		{1, 6, 1, true},
+		{2, 20, 1, true},
+		{2, 20, 1, false},
+		{4, 6, 3, true},
+		{4, 6, 3, false},
+		{5, 4, 2, true},
+		{3, 8, 0, true},
+		{3, 5, 3, false},
+	}
+
+	for _, tt := range tests {
+		d := keepOdds(tt.n, tt.sides, tt.keep, tt.highest)
+		expected := bruteKeepOdds(tt.n, tt.sides, tt.keep, tt.highest)
+		for i, p := range d.P {
+			if math.Abs(p-expected[d.Min+i]) > oddsEpsilon {
+				t.Errorf("keepOdds(%v, %v, %v, %v): P(%v) = %v, expected %v",
+					tt.n, tt.sides, tt.keep, tt.highest, d.Min+i, p, expected[d.Min+i])
+			}
+		}
+		for v, p := range expected {
+			if p > 0 && (v < d.Min || v > d.max()) {
+				t.Errorf("keepOdds(%v, %v, %v, %v): outcome %v is missing",
+					tt.n, tt.sides, tt.keep, tt.highest, v)
+			}
+		}
+	}
+}
+
+func TestParseOdds(t *testing.T) {
+	tests := []struct {
+		desc     string
+		min, max int
+		mean     float64
+		op       string
+		target   int
+		chance   float64
+	}{
+		{"1d20", 1, 20, 10.5, ">=", 15, 0.3},
+		{"2d6", 2, 12, 7, "=", 7, 1.0 / 6},
+		{"2d6+3", 5, 15, 10, "<", 6, 1.0 / 36},
+		{"2d20kh1", 1, 20, 13.825, ">=", 15, 1 - 0.7*0.7},
+		{"2d20kl1", 1, 20, 7.175, ">=", 15, 0.3 * 0.3},
+		{"2d20d1", 1, 20, 13.825, ">", 19, 1 - 0.95*0.95},
+		{"4d6kh3", 3, 18, 12.2446, "=", 18, 21.0 / 1296},
+		{"4df", -4, 4, 0, "=", 0, 19.0 / 81},
+		{"1df+2", 1, 3, 2, "<=", 2, 2.0 / 3},
+		{"2d6v5", 0, 2, 2.0 / 3, "=", 2, 1.0 / 9},
+		{"1d6ev8", 0, 1, 5.0 / 36, "=", 1, 5.0 / 36},
+		{"1d6rv5", 0, 0, 0, "=", 0, 4.0 / 6},
+		{"1d6rv5", 0, 0, 0, "=", 1, 10.0 / 36},
+	}
+
+	for _, tt := range tests {
+		d, err := parseOdds(tt.desc)
+		if err != nil {
+			t.Errorf("parseOdds(%q) returned error: %s", tt.desc, err)
+			continue
+		}
+
+		total := 0.0
+		for _, p := range d.P {
+			total += p
+		}
+		if math.Abs(total-1) > 1e-6 {
+			t.Errorf("parseOdds(%q): probabilities sum to %v", tt.desc, total)
+		}
+		if tt.max > 0 && (d.Min != tt.min || d.max() != tt.max) {
+			t.Errorf("parseOdds(%q): range %v to %v, expected %v to %v", tt.desc, d.Min, d.max(), tt.min, tt.max)
+		}
+		if tt.max > 0 && math.Abs(d.mean()-tt.mean) > 1e-4 {
+			t.Errorf("parseOdds(%q): mean %v, expected %v", tt.desc, d.mean(), tt.mean)
+		}
+		if p := d.probability(tt.op, tt.target); math.Abs(p-tt.chance) > 1e-6 {
+			t.Errorf("parseOdds(%q): P(%s %v) = %v, expected %v", tt.desc, tt.op, tt.target, p, tt.chance)
+		}
+	}
+}
+
+func TestParseOddsErrors(t *testing.T) {
+	for _, desc := range []string{"", "d20", "2d0", "2d6k3", "0d6v4", "2d1v1", "3g", "10000d10000"} {
+		if _, err := parseOdds(desc); err == nil {
+			t.Errorf("parseOdds(%q) returned no error", desc)
+		}
+	}
+}
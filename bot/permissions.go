@@ -0,0 +1,135 @@
+package bot
+
+import "fmt"
+
+// PermissionConfig represents the requirements for executing a command.
+// A sender is allowed to execute a command when any of the requirements is met.
+type PermissionConfig struct {
+	// Everyone allows all senders.
+	Everyone bool
+
+	// Registered allows all registered Mumble users.
+	Registered bool
+
+	// Users allows the registered Mumble users with the given names.
+	Users []string
+
+	// Hashes allows the Mumble users with the given certificate hashes.
+	Hashes []string
+
+	// Groups allows the registered Mumble users that are a member
+	// of the given groups in the ACL of the root channel.
+	Groups []string
+
+	// Tokens allows the API tokens with the given names.
+	Tokens []string
+
+	// Chat allows the Matrix or Telegram users with the given IDs.
+	// Telegram users are identified by their numeric user ID.
+	// Other requirements, including Everyone, do not apply to these users.
+	Chat []string
+}
+
+// allows returns true if a sender meets the requirements.
+// Matrix and Telegram users are only allowed if they are listed in Chat.
+func (p *PermissionConfig) allows(c *Client, s *Sender) bool {
+	if s != nil && s.Chat != "" {
+		return contains(p.Chat, s.Chat)
+	}
+
+	if p.Everyone {
+		return true
+	}
+
+	if s == nil {
+		return false
+	}
+
+	if s.Token != "" {
+		return contains(p.Tokens, s.Token)
+	}
+
+	if s.Hash != "" && contains(p.Hashes, s.Hash) {
+		return true
+	}
+
+	if !s.Registered {
+		return false
+	}
+
+	if p.Registered || contains(p.Users, s.Name) {
+		return true
+	}
+
+	if s.User != nil {
+		for _, g := range p.Groups {
+			if c.Mumble.InGroup(s.User, g) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// usesGroups returns true if any permission requires membership of an ACL group.
+func (m *MumbleConfig) usesGroups() bool {
+	for _, p := range m.Permissions {
+		if p != nil && len(p.Groups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedSubjects contains the permission subjects that are denied unless configured.
+// The `default` permissions do not apply to these subjects.
+var restrictedSubjects = map[string]bool{
+	auditSubject:  true,
+	moveSubject:   true,
+	muteSubject:   true,
+	deafenSubject: true,
+	kickSubject:   true,
+	banSubject:    true,
+}
+
+// checkPermission returns an error if the sender is not allowed to execute a command.
+// Permissions are configured per command, with the `default` permissions
+// applying to commands without configured permissions.
+// Restricted subjects are denied if they have no configured permissions,
+// and so are Matrix and Telegram users for any command.
+// Commands issued by the bot itself are always allowed.
+func (c *Client) checkPermission(ctx *Context, cmd string) error {
+	if ctx.trusted() {
+		return nil
+	}
+	s := ctx.Sender
+
+	permissions := c.Config.Mumble.Permissions
+	restricted := restrictedSubjects[cmd]
+	p, ok := permissions[cmd]
+	if !ok && !restricted {
+		p, ok = permissions[defaultSubject]
+	}
+	if (!ok || p == nil) && !restricted && (s == nil || s.Chat == "") {
+		return nil
+	}
+	if p != nil && p.allows(c, s) {
+		return nil
+	}
+
+	if s == nil {
+		return fmt.Errorf("anonymous senders are not allowed to use %q", cmd)
+	}
+	return fmt.Errorf("%s is not allowed to use %q", s.Name, cmd)
+}
+
+// contains returns true if a list of strings contains the given string.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
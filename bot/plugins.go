@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// maxPluginOutput is the maximum size of the response of a plugin in bytes.
+const maxPluginOutput = 1 << 20
+
+// PluginRequest is the JSON request sent to a plugin on its standard input.
+// The Type is either "describe", to request the commands provided by the plugin,
+// or "command" to execute one of these commands.
+type PluginRequest struct {
+	Type     string        `json:"type"`
+	Command  string        `json:"command,omitempty"`
+	Args     []string      `json:"args,omitempty"`
+	Sender   *PluginSender `json:"sender,omitempty"`
+	Channel  string        `json:"channel,omitempty"`
+	Platform string        `json:"platform,omitempty"`
+}
+
+// PluginSender describes the sender of a command in a PluginRequest.
+type PluginSender struct {
+	Name       string `json:"name"`
+	Hash       string `json:"hash,omitempty"`
+	Registered bool   `json:"registered"`
+}
+
+// PluginDescription is the JSON response of a plugin to a "describe" request.
+type PluginDescription struct {
+	Commands []*PluginCommand `json:"commands"`
+}
+
+// PluginCommand describes a command provided by a plugin.
+type PluginCommand struct {
+	Name     string     `json:"name"`
+	Summary  string     `json:"summary"`
+	Args     []Argument `json:"args"`
+	Examples []string   `json:"examples"`
+}
+
+// PluginResponse is the JSON response of a plugin to a "command" request.
+type PluginResponse struct {
+	Actions []*PluginAction `json:"actions"`
+	Error   string          `json:"error"`
+}
+
+// PluginAction is an action to perform in response to a command:
+//
+//	{"type": "reply", "text": "plain text"} or {"type": "reply", "html": "<b>HTML</b>"}
+//	{"type": "play", "file": "clip name", "loop": false}
+//	{"type": "sticker", "name": "sticker name"}
+//	{"type": "chat", "text": "message for the linked Matrix or Telegram chat"} or {"type": "chat", "html": "<b>HTML</b>"}
+//	{"type": "matrix", "text": "message for the linked Matrix room"}
+type PluginAction struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	HTML string `json:"html"`
+	File string `json:"file"`
+	Loop bool   `json:"loop"`
+	Name string `json:"name"`
+}
+
+// LoadPlugins (re)loads the commands of all plugins in the configured plugin directory.
+// Commands with the same name as an existing command are ignored.
+func (c *Client) LoadPlugins() error {
+	c.Lock()
+	for _, name := range c.plugins {
+		delete(c.commands, name)
+	}
+	c.plugins = nil
+	c.Unlock()
+
+	dir := c.Config.Mumble.Plugins.Directory
+	if dir == "" {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range files {
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		var desc PluginDescription
+		if err := c.runPlugin(path, &PluginRequest{Type: "describe"}, &desc); err != nil {
+			log.Printf("Error loading plugin %q: %s", path, err)
+			continue
+		}
+
+		for _, pc := range desc.Commands {
+			if pc.Name == "" || c.Command(pc.Name) != nil {
+				log.Printf("Ignoring command %q of plugin %q", pc.Name, path)
+				continue
+			}
+
+			c.Lock()
+			c.commands[pc.Name] = &Command{
+				Summary:  pc.Summary,
+				Args:     pc.Args,
+				Examples: pc.Examples,
+				Handler:  pluginHandler(path),
+			}
+			c.plugins = append(c.plugins, pc.Name)
+			c.Unlock()
+			log.Printf("Loaded command %q from plugin %q", pc.Name, path)
+		}
+	}
+
+	return nil
+}
+
+// pluginHandler returns a handler executing a command of the plugin at the given path.
+func pluginHandler(path string) CommandHandler {
+	return func(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+		req := &PluginRequest{
+			Type:     "command",
+			Command:  cmd,
+			Args:     args,
+			Channel:  ctx.ChannelName(),
+			Platform: string(ctx.Source),
+		}
+		if args == nil {
+			req.Args = []string{}
+		}
+		if s := ctx.Sender; s != nil {
+			req.Sender = &PluginSender{Name: s.Name, Hash: s.Hash, Registered: s.Registered}
+		}
+
+		var res PluginResponse
+		if err := c.runPlugin(path, req, &res); err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+		if res.Error != "" {
+			return Errorf("Error: %s", html.EscapeString(res.Error))
+		}
+
+		resp = Reply("")
+		replies := make([]string, 0, len(res.Actions))
+		for _, a := range res.Actions {
+			reply, err := c.performPluginAction(a)
+			if err != nil {
+				resp.Status = StatusError
+				replies = append(replies, fmt.Sprintf("Error: %s", html.EscapeString(err.Error())))
+			} else if reply != "" {
+				replies = append(replies, reply)
+			}
+		}
+		resp.HTML = strings.Join(replies, "<br/>")
+		return resp
+	}
+}
+
+// performPluginAction performs an action returned by a plugin,
+// and returns the reply to send, if any.
+func (c *Client) performPluginAction(a *PluginAction) (string, error) {
+	switch a.Type {
+	case "reply":
+		if a.HTML != "" {
+			return a.HTML, nil
+		}
+		return html.EscapeString(a.Text), nil
+	case "play":
+		if a.File == "" || strings.Contains(a.File, "..") {
+			return "", fmt.Errorf("invalid file: %q", a.File)
+		}
+		file := filepath.Join(c.Config.Mumble.Sounds.Clips, a.File) + SoundExtension
+		if a.Loop {
+			return "", c.PlayHold(file)
+		}
+		return "", c.PlaySound(file)
+	case "sticker":
+		return "", c.SendSticker(a.Name)
+	case "chat":
+		if a.HTML != "" {
+			return "", c.SendMessage(ParseHTML(a.HTML))
+		}
+		return "", c.SendText(a.Text)
+	case "matrix":
+		if c.Matrix == nil {
+			return "", fmt.Errorf("matrix is not configured")
+		}
+		_, err := c.Matrix.SendText(a.Text)
+		return "", err
+	default:
+		return "", fmt.Errorf("unknown action: %q", a.Type)
+	}
+}
+
+// runPlugin executes a plugin with a JSON request on its standard input,
+// and decodes the JSON response from its standard output.
+// Plugins are executed like scripts, see CommandShell.
+func (c *Client) runPlugin(path string, req *PluginRequest, res interface{}) error {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	p := &process{
+		Path:    path,
+		Stdin:   bytes.NewReader(in),
+		Timeout: c.Config.Mumble.Plugins.Timeout,
+		Limit:   maxPluginOutput,
+	}
+	out, stderr, err := p.run()
+	if stderr.Len() > 0 {
+		log.Printf("Plugin %q: %s", path, stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("running plugin %q: %w", filepath.Base(path), err)
+	}
+	if out.Truncated {
+		return fmt.Errorf("response of plugin %q exceeds %v bytes", filepath.Base(path), maxPluginOutput)
+	}
+
+	if err := json.Unmarshal(out.Bytes(), res); err != nil {
+		return fmt.Errorf("invalid response from plugin %q: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPollDuration is the maximum duration of a poll.
+const maxPollDuration = 24 * time.Hour
+
+// poll represents a poll with votes by user identity.
+type poll struct {
+	sync.Mutex
+	question string
+	options  []string
+	votes    map[string]int
+	timer    *time.Timer
+	closed   bool
+}
+
+// vote registers the vote of a user for an option, replacing any earlier vote.
+func (p *poll) vote(user string, option int) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("the poll is closed")
+	}
+	if option < 1 || option > len(p.options) {
+		return fmt.Errorf("invalid option %v, choose 1-%v", option, len(p.options))
+	}
+
+	p.votes[user] = option
+	return nil
+}
+
+// open returns whether the poll is still open.
+func (p *poll) open() bool {
+	p.Lock()
+	defer p.Unlock()
+	return !p.closed
+}
+
+// close closes the poll, and returns false if it was already closed.
+func (p *poll) close() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return false
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.closed = true
+	return true
+}
+
+// counts returns the number of votes for every option, and the total number of votes.
+func (p *poll) counts() (counts []int, total int) {
+	p.Lock()
+	defer p.Unlock()
+
+	counts = make([]int, len(p.options))
+	for _, o := range p.votes {
+		counts[o-1]++
+	}
+	return counts, len(p.votes)
+}
+
+// html returns the results of the poll as HTML, translated using the given catalog.
+func (p *poll) html(cat Catalog) string {
+	counts, total := p.counts()
+	lines := make([]string, len(p.options))
+	for i, o := range p.options {
+		votes := cat.Sprintf(plural(counts[i], "%v vote", "%v votes"), counts[i])
+		lines[i] = "<li>" + cat.Sprintf("%s: %s (%.0f%%)", html.EscapeString(o), votes, percentage(counts[i], total)) + "</li>"
+	}
+	return cat.Sprintf("Poll: <b>%s</b><ol>%s</ol>%s", html.EscapeString(p.question), strings.Join(lines, ""),
+		cat.Sprintf(plural(total, "%v vote", "%v votes"), total))
+}
+
+// CommandPoll starts, shows or closes a poll.
+func CommandPoll(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	switch {
+	case len(args) == 0:
+		p := c.currentPoll()
+		if p == nil {
+			return Replyf("There is no poll, start one using: %s &lt;question&gt; &lt;option&gt; &lt;option&gt;...", cmd)
+		}
+		return Reply(p.html(c.catalog(ctx)))
+	case len(args) == 1 && args[0] == "close":
+		return c.closePoll(ctx)
+	}
+
+	var timeout time.Duration
+	if d, err := time.ParseDuration(args[0]); err == nil {
+		if d <= 0 || d > maxPollDuration {
+			return Errorf("Error: the duration must be positive and at most %v", maxPollDuration)
+		}
+		timeout, args = d, args[1:]
+	}
+	if len(args) < 3 {
+		return Usagef("Usage: %s [duration] &lt;question&gt; &lt;option&gt; &lt;option&gt;...", cmd)
+	}
+
+	p := &poll{
+		question: args[0],
+		options:  args[1:],
+		votes:    make(map[string]int),
+	}
+
+	c.Lock()
+	if c.poll != nil && c.poll.open() {
+		c.Unlock()
+		return Errorf("Error: a poll is already open, close it using: %s close", cmd)
+	}
+	c.poll = p
+	c.Unlock()
+
+	if timeout > 0 {
+		p.Lock()
+		p.timer = time.AfterFunc(timeout, func() {
+			if p.open() {
+				ctx := ctx.scheduled()
+				c.SendReply(ctx, c.closePoll(ctx))
+			}
+		})
+		p.Unlock()
+	}
+
+	if err := c.SendMessage(ParseHTML(p.html(c.userCatalog("")))); err != nil {
+		log.Printf("Error sending poll: %s", err)
+	}
+	cat := c.catalog(ctx)
+	return Reply(p.html(cat) + "<br/>" + cat.Translate("Vote using: vote &lt;number&gt;"))
+}
+
+// CommandVote votes in the current poll.
+func CommandVote(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) != 1 {
+		return Usagef("Usage: %s &lt;number&gt;", cmd)
+	}
+
+	p := c.currentPoll()
+	if p == nil {
+		return Errorf("Error: there is no poll")
+	}
+
+	option, err := strconv.Atoi(args[0])
+	if err != nil {
+		return Usagef("Usage: %s &lt;number&gt;", cmd)
+	}
+	if err := p.vote(senderIdentity(ctx.Sender), option); err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+
+	return Reply(p.html(c.catalog(ctx)))
+}
+
+// currentPoll returns the current or last poll, if any.
+func (c *Client) currentPoll() *poll {
+	c.Lock()
+	defer c.Unlock()
+	return c.poll
+}
+
+// closePoll closes the current poll, sends the results to the linked chat,
+// and returns the results translated for the context.
+func (c *Client) closePoll(ctx *Context) *Result {
+	p := c.currentPoll()
+	if p == nil || !p.close() {
+		return Errorf("Error: there is no open poll")
+	}
+
+	cat := c.userCatalog("")
+	if err := c.SendMessage(ParseHTML(cat.Sprintf("Poll closed. %s", p.html(cat)))); err != nil {
+		log.Printf("Error sending poll results: %s", err)
+	}
+	return Replyf("Poll closed. %s", p.html(c.catalog(ctx)))
+}
+
+// percentage returns n as a percentage of total.
+func percentage(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// plural returns the singular or plural form of a word or format string.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
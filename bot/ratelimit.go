@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig represents the rate limits of a command,
+// which apply to every user and to all users combined.
+type RateLimitConfig struct {
+	User   *LimitConfig
+	Global *LimitConfig
+}
+
+// LimitConfig represents a token bucket rate limit allowing Count executions per Period,
+// and a minimum time between two executions.
+type LimitConfig struct {
+	Count    int
+	Period   time.Duration
+	Cooldown time.Duration
+}
+
+// rateLimitPruneInterval is the interval at which unused buckets are removed.
+const rateLimitPruneInterval = 10 * time.Minute
+
+// tokenBucket contains the state of a rate limit.
+// The bucket is the same as a new bucket after it expires.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	last    time.Time
+	expires time.Time
+}
+
+// wait returns the time until the bucket allows an execution.
+func (b *tokenBucket) wait(l *LimitConfig, now time.Time) (wait time.Duration) {
+	if l.Cooldown > 0 && !b.last.IsZero() {
+		wait = l.Cooldown - now.Sub(b.last)
+	}
+
+	if l.Count > 0 && l.Period > 0 {
+		b.refill(l, now)
+		if b.tokens < 1 {
+			refill := time.Duration((1 - b.tokens) * float64(l.Period) / float64(l.Count))
+			if refill > wait {
+				wait = refill
+			}
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *tokenBucket) refill(l *LimitConfig, now time.Time) {
+	if b.updated.IsZero() {
+		b.tokens = float64(l.Count)
+	} else {
+		b.tokens += float64(now.Sub(b.updated)) * float64(l.Count) / float64(l.Period)
+		b.tokens = math.Min(b.tokens, float64(l.Count))
+	}
+	b.updated = now
+}
+
+// take registers an execution.
+func (b *tokenBucket) take(now time.Time) {
+	b.tokens--
+	b.last = now
+}
+
+// idle returns the time after which an unused bucket is full and its cooldown has passed.
+func (l *LimitConfig) idle() time.Duration {
+	if l.Cooldown > l.Period {
+		return l.Cooldown
+	}
+	return l.Period
+}
+
+// rateLimiter enforces the configured rate limits of commands.
+type rateLimiter struct {
+	sync.Mutex
+	buckets map[string]*tokenBucket
+	limited map[string]uint64
+	pruned  time.Time
+}
+
+// newRateLimiter returns an initialized rateLimiter.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		limited: make(map[string]uint64),
+	}
+}
+
+// bucket returns the bucket with the given key.
+func (r *rateLimiter) bucket(key string) *tokenBucket {
+	b, ok := r.buckets[key]
+	if !ok {
+		b = new(tokenBucket)
+		r.buckets[key] = b
+	}
+	return b
+}
+
+// allow registers an execution of a command by a user if the rate limits allow it.
+// Otherwise the time until the command is allowed again is returned.
+func (r *rateLimiter) allow(config *RateLimitConfig, cmd, user string) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	r.prune(now)
+
+	limits := make(map[*tokenBucket]*LimitConfig, 2)
+	if config.Global != nil {
+		limits[r.bucket(cmd)] = config.Global
+	}
+	if config.User != nil {
+		limits[r.bucket(cmd+"\x00"+user)] = config.User
+	}
+
+	var wait time.Duration
+	for b, l := range limits {
+		if w := b.wait(l, now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		r.limited[cmd]++
+		return wait
+	}
+
+	for b, l := range limits {
+		b.take(now)
+		if expires := now.Add(l.idle()); expires.After(b.expires) {
+			b.expires = expires
+		}
+	}
+	return 0
+}
+
+// prune removes the expired buckets, at most once per prune interval.
+// It must be called with the lock held.
+func (r *rateLimiter) prune(now time.Time) {
+	if now.Sub(r.pruned) < rateLimitPruneInterval {
+		return
+	}
+	r.pruned = now
+
+	for key, b := range r.buckets {
+		if !now.Before(b.expires) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// stats returns the number of rate limited executions per command.
+func (r *rateLimiter) stats() map[string]uint64 {
+	r.Lock()
+	defer r.Unlock()
+
+	stats := make(map[string]uint64, len(r.limited))
+	for cmd, n := range r.limited {
+		stats[cmd] = n
+	}
+	return stats
+}
+
+// checkRateLimit returns an error if the sender has exceeded the rate limits of a command.
+// Rate limits are configured per command, with the `default` rate limits
+// applying to commands without configured rate limits.
+// Commands issued by the bot itself are never limited.
+func (c *Client) checkRateLimit(ctx *Context, cmd string) error {
+	if ctx.trusted() {
+		return nil
+	}
+
+	limits := c.Config.Mumble.RateLimits
+	config, ok := limits[cmd]
+	if !ok {
+		config, ok = limits[defaultSubject]
+	}
+	if !ok || config == nil {
+		return nil
+	}
+
+	wait := c.rateLimiter.allow(config, cmd, senderIdentity(ctx.Sender))
+	if wait == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("try again in %v", time.Duration(math.Ceil(wait.Seconds()))*time.Second)
+}
+
+// RateLimited returns the number of rate limited executions per command.
+func (c *Client) RateLimited() map[string]uint64 {
+	return c.rateLimiter.stats()
+}
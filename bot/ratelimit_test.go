@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name   string
+		limit  *LimitConfig
+		takes  []time.Duration
+		at     time.Duration
+		expect time.Duration
+	}{
+		{"empty", &LimitConfig{Count: 2, Period: time.Minute}, nil, 0, 0},
+		{"burst", &LimitConfig{Count: 2, Period: time.Minute}, []time.Duration{0}, 0, 0},
+		{"exhausted", &LimitConfig{Count: 2, Period: time.Minute}, []time.Duration{0, 0}, 0, 30 * time.Second},
+		{"partial refill", &LimitConfig{Count: 2, Period: time.Minute}, []time.Duration{0, 0}, 20 * time.Second, 10 * time.Second},
+		{"refilled", &LimitConfig{Count: 2, Period: time.Minute}, []time.Duration{0, 0}, 30 * time.Second, 0},
+		{"refill capped", &LimitConfig{Count: 2, Period: time.Minute}, []time.Duration{0, time.Hour, time.Hour}, time.Hour, 30 * time.Second},
+		{"cooldown", &LimitConfig{Cooldown: 10 * time.Second}, []time.Duration{0}, 4 * time.Second, 6 * time.Second},
+		{"cooldown passed", &LimitConfig{Cooldown: 10 * time.Second}, []time.Duration{0}, 10 * time.Second, 0},
+		{"cooldown longest", &LimitConfig{Count: 10, Period: time.Second, Cooldown: time.Minute}, []time.Duration{0}, 0, time.Minute},
+		{"refill longest", &LimitConfig{Count: 1, Period: time.Minute, Cooldown: time.Second}, []time.Duration{0}, 0, time.Minute},
+	}
+
+	for _, tt := range tests {
+		b := new(tokenBucket)
+		for _, at := range tt.takes {
+			if w := b.wait(tt.limit, start.Add(at)); w != 0 {
+				t.Errorf("%s: wait before take at %v = %v, expected 0", tt.name, at, w)
+			}
+			b.take(start.Add(at))
+		}
+		if w := b.wait(tt.limit, start.Add(tt.at)); w != tt.expect {
+			t.Errorf("%s: wait = %v, expected %v", tt.name, w, tt.expect)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	config := &RateLimitConfig{
+		User:   &LimitConfig{Count: 1, Period: time.Hour},
+		Global: &LimitConfig{Count: 2, Period: time.Hour},
+	}
+	r := newRateLimiter()
+
+	if w := r.allow(config, "play", "alice"); w != 0 {
+		t.Errorf("first execution limited for %v", w)
+	}
+	if w := r.allow(config, "play", "alice"); w == 0 {
+		t.Errorf("second execution by the same user allowed")
+	}
+	if w := r.allow(config, "play", "bob"); w != 0 {
+		t.Errorf("execution by another user limited for %v", w)
+	}
+	if w := r.allow(config, "play", "carol"); w == 0 {
+		t.Errorf("execution over the global limit allowed")
+	}
+	if w := r.allow(config, "clip", "alice"); w != 0 {
+		t.Errorf("execution of another command limited for %v", w)
+	}
+
+	if stats := r.stats(); stats["play"] != 2 || len(stats) != 1 {
+		t.Errorf("stats = %v, expected 2 limited executions of play", stats)
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	r := newRateLimiter()
+	now := time.Now()
+	r.buckets["expired"] = &tokenBucket{expires: now.Add(-time.Second)}
+	r.buckets["active"] = &tokenBucket{expires: now.Add(time.Minute)}
+
+	r.prune(now)
+	if _, ok := r.buckets["expired"]; ok {
+		t.Errorf("expired bucket was not removed")
+	}
+	if _, ok := r.buckets["active"]; !ok {
+		t.Errorf("active bucket was removed")
+	}
+
+	r.buckets["expired"] = &tokenBucket{expires: now}
+	r.prune(now.Add(time.Second))
+	if _, ok := r.buckets["expired"]; !ok {
+		t.Errorf("buckets were pruned again within the prune interval")
+	}
+}
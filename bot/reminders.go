@@ -0,0 +1,464 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Kinds of reminders.
+const (
+	ReminderRemind    = "remind"
+	ReminderTimer     = "timer"
+	ReminderCountdown = "countdown"
+)
+
+const (
+	// maxReminderDuration is the maximum duration of a reminder or timer.
+	maxReminderDuration = 30 * 24 * time.Hour
+
+	// maxCountdown is the maximum duration of a countdown.
+	maxCountdown = 5 * time.Minute
+
+	// maxRemindersPerUser is the maximum number of pending reminders per user.
+	maxRemindersPerUser = 10
+)
+
+// countdownTicks contains the remaining seconds at which a countdown is announced.
+var countdownTicks = []int{60, 30, 10, 5, 4, 3, 2, 1}
+
+// Reminder represents a pending reminder, timer or countdown.
+// Reminders with a User are sent privately to that user,
+// others are sent to the Channel.
+type Reminder struct {
+	ID       int
+	Kind     string
+	Text     string
+	Time     time.Time
+	Duration time.Duration
+	Owner    string
+	User     string `json:",omitempty"`
+	Channel  string `json:",omitempty"`
+
+	// Due is set if the reminder has fired while its user was offline.
+	Due bool `json:",omitempty"`
+}
+
+// reminders contains the pending reminders, which are persisted to a state file.
+type reminders struct {
+	sync.Mutex
+	file    string
+	nextID  int
+	entries map[int]*Reminder
+	timers  map[int][]*time.Timer
+}
+
+// newReminders returns a set of reminders persisted in the given file.
+// The reminders are not scheduled.
+func newReminders(file string) (*reminders, error) {
+	r := &reminders{
+		file:    file,
+		nextID:  1,
+		entries: make(map[int]*Reminder),
+		timers:  make(map[int][]*time.Timer),
+	}
+	if file == "" {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+
+	var entries []*Reminder
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return r, err
+	}
+	for _, e := range entries {
+		r.entries[e.ID] = e
+		if e.ID >= r.nextID {
+			r.nextID = e.ID + 1
+		}
+	}
+	return r, nil
+}
+
+// save writes the reminders to the state file.
+// It must be called with the lock held.
+func (r *reminders) save() {
+	if r.file == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(r.list(), "", "  ")
+	if err != nil {
+		log.Printf("Error encoding reminders: %s", err)
+		return
+	}
+
+	tmp := r.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+		log.Printf("Error saving reminders: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, r.file); err != nil {
+		log.Printf("Error saving reminders: %s", err)
+	}
+}
+
+// list returns the reminders sorted by time.
+// It must be called with the lock held.
+func (r *reminders) list() []*Reminder {
+	list := make([]*Reminder, 0, len(r.entries))
+	for _, e := range r.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+	return list
+}
+
+// add adds a reminder, assigning it an ID.
+func (r *reminders) add(e *Reminder) error {
+	r.Lock()
+	defer r.Unlock()
+
+	n := 0
+	for _, o := range r.entries {
+		if o.Owner == e.Owner {
+			n++
+		}
+	}
+	if n >= maxRemindersPerUser {
+		return fmt.Errorf("you may not have more than %v pending reminders", maxRemindersPerUser)
+	}
+
+	e.ID = r.nextID
+	r.nextID++
+	r.entries[e.ID] = e
+	r.save()
+	return nil
+}
+
+// remove removes a reminder and stops its timers.
+func (r *reminders) remove(id int) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, t := range r.timers[id] {
+		t.Stop()
+	}
+	delete(r.timers, id)
+	delete(r.entries, id)
+	r.save()
+}
+
+// get returns the reminder with an ID, or nil.
+func (r *reminders) get(id int) *Reminder {
+	r.Lock()
+	defer r.Unlock()
+	return r.entries[id]
+}
+
+// setDue marks a reminder as due for delivery when its user connects.
+func (r *reminders) setDue(id int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if e, ok := r.entries[id]; ok {
+		e.Due = true
+		delete(r.timers, id)
+		r.save()
+	}
+}
+
+// schedule schedules a reminder, calling tick with the remaining seconds
+// for countdowns, and fire when the reminder is due.
+func (r *reminders) schedule(e *Reminder, tick func(e *Reminder, n int), fire func(e *Reminder)) {
+	r.Lock()
+	defer r.Unlock()
+
+	if e.Due {
+		return
+	}
+
+	remaining := time.Until(e.Time)
+	if e.Kind == ReminderCountdown {
+		for _, n := range countdownTicks {
+			n := n
+			d := remaining - time.Duration(n)*time.Second
+			if d >= 0 && time.Duration(n)*time.Second < e.Duration {
+				r.timers[e.ID] = append(r.timers[e.ID], time.AfterFunc(d, func() { tick(e, n) }))
+			}
+		}
+	}
+	r.timers[e.ID] = append(r.timers[e.ID], time.AfterFunc(remaining, func() { fire(e) }))
+}
+
+// visible returns the reminders owned by the sender, or sent to the given channel.
+func (r *reminders) visible(owner, channel string) []*Reminder {
+	r.Lock()
+	defer r.Unlock()
+
+	var list []*Reminder
+	for _, e := range r.list() {
+		if e.Owner == owner || (e.User == "" && e.Channel == channel) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// due returns the reminders that are due for the given user.
+func (r *reminders) due(user string) []*Reminder {
+	r.Lock()
+	defer r.Unlock()
+
+	var list []*Reminder
+	for _, e := range r.list() {
+		if e.Due && e.User == user {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// loadReminders loads and schedules the persisted reminders.
+func (c *Client) loadReminders() (err error) {
+	c.reminders, err = newReminders(c.Config.Mumble.Reminders.State)
+	if err != nil {
+		return
+	}
+
+	c.reminders.Lock()
+	list := c.reminders.list()
+	c.reminders.Unlock()
+
+	for _, e := range list {
+		c.reminders.schedule(e, c.tickReminder, c.fireReminder)
+	}
+	return
+}
+
+// addReminder adds and schedules a reminder for the sender of a context.
+// Reminders of senders that are not Mumble users, or that have the channel scope,
+// are sent to the channel of the context.
+func (c *Client) addReminder(ctx *Context, kind string, channel bool, d time.Duration, text string) (*Reminder, error) {
+	s := ctx.Sender
+	e := &Reminder{
+		Kind:     kind,
+		Text:     text,
+		Time:     time.Now().Add(d),
+		Duration: d,
+		Owner:    senderIdentity(s),
+	}
+	if channel || s == nil || s.User == nil {
+		e.Channel = ctx.ChannelName()
+	} else {
+		e.User = senderIdentity(s)
+	}
+
+	if err := c.reminders.add(e); err != nil {
+		return nil, err
+	}
+	c.reminders.schedule(e, c.tickReminder, c.fireReminder)
+	return e, nil
+}
+
+// tickReminder announces the remaining seconds of a countdown.
+func (c *Client) tickReminder(e *Reminder, n int) {
+	c.sendReminder(e, Replyf("%v...", n))
+}
+
+// fireReminder sends the message of a reminder and plays the configured sound.
+// If the user of the reminder is not online, it is delivered when they connect.
+func (c *Client) fireReminder(e *Reminder) {
+	if e.User != "" && c.reminderUser(e.User) == nil {
+		c.reminders.setDue(e.ID)
+		return
+	}
+
+	c.reminders.remove(e.ID)
+	c.sendReminder(e, Reply(reminderMessage(c.userCatalog(e.Owner), e)))
+	c.PlayNotification(c.Config.Mumble.Reminders.Sound)
+}
+
+// deliverReminders delivers the reminders that became due while a user was offline.
+func (c *Client) deliverReminders(u *gumble.User) {
+	for _, e := range c.reminders.due(senderIdentity(MumbleSender(u))) {
+		c.reminders.remove(e.ID)
+		cat := c.userCatalog(e.Owner)
+		u.Send(cat.Sprintf("%s (due at %s)", reminderMessage(cat, e), e.Time.Format("2006-01-02 15:04")))
+	}
+}
+
+// sendReminder sends a reply to the user or channel of a reminder,
+// translated for the owner of the reminder.
+// The reply is sent in a scheduled context, in the channel of the bot if the channel no longer exists.
+func (c *Client) sendReminder(e *Reminder, r *Result) {
+	ctx := c.NewContext(SourceSchedule, nil)
+	if e.User != "" {
+		u := c.reminderUser(e.User)
+		if u == nil {
+			return
+		}
+		ctx.Reply = &ReplyTarget{Users: []*gumble.User{u}}
+	} else if channel := c.Mumble.FindChannel(e.Channel); channel != nil {
+		ctx.Channel = channel
+	}
+
+	r = r.localize(c.userCatalog(e.Owner))
+	r.route = RouteSource
+	c.SendReply(ctx, r)
+}
+
+// reminderUser returns the online user with the given identity, or nil.
+func (c *Client) reminderUser(user string) *gumble.User {
+	for _, u := range c.Mumble.Users {
+		if u.Hash == user || (u.Hash == "" && u.Name == user) {
+			return u
+		}
+	}
+	return nil
+}
+
+// reminderMessage returns the message that is sent when a reminder fires,
+// translated using the given catalog.
+func reminderMessage(cat Catalog, e *Reminder) string {
+	switch e.Kind {
+	case ReminderTimer:
+		if e.Text != "" {
+			return cat.Sprintf("Timer %q of %v has finished", html.EscapeString(e.Text), e.Duration)
+		}
+		return cat.Sprintf("Timer of %v has finished", e.Duration)
+	case ReminderCountdown:
+		return cat.Translate("Go!")
+	default:
+		return cat.Sprintf("Reminder: %s", html.EscapeString(e.Text))
+	}
+}
+
+// parseReminderArgs parses the optional scope and the duration of a reminder or timer.
+func parseReminderArgs(args []string) (channel bool, d time.Duration, rest []string, err error) {
+	if len(args) > 0 && (args[0] == "me" || args[0] == "channel") {
+		channel, args = args[0] == "channel", args[1:]
+	}
+	if len(args) == 0 {
+		return false, 0, nil, fmt.Errorf("missing duration")
+	}
+
+	d, err = time.ParseDuration(args[0])
+	if err != nil {
+		return
+	}
+	if d <= 0 || d > maxReminderDuration {
+		return false, 0, nil, fmt.Errorf("the duration must be positive and at most %v", maxReminderDuration)
+	}
+	return channel, d, args[1:], nil
+}
+
+// CommandRemind sets a reminder with a message.
+func CommandRemind(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	channel, d, args, err := parseReminderArgs(args)
+	if err != nil || len(args) == 0 {
+		return Usagef("Usage: %s [me|channel] &lt;duration&gt; &lt;message&gt;", cmd)
+	}
+
+	e, err := c.addReminder(ctx, ReminderRemind, channel, d, strings.Join(args, " "))
+	if err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	return Replyf("Reminder #%v set for %s (in %v)", e.ID, e.Time.Format("15:04:05"), d)
+}
+
+// CommandTimer starts a timer.
+func CommandTimer(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	channel, d, args, err := parseReminderArgs(args)
+	if err != nil {
+		return Usagef("Usage: %s [me|channel] &lt;duration&gt; [label]", cmd)
+	}
+
+	e, err := c.addReminder(ctx, ReminderTimer, channel, d, strings.Join(args, " "))
+	if err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	return Replyf("Timer #%v of %v started", e.ID, d)
+}
+
+// CommandCountdown starts a countdown in the channel.
+func CommandCountdown(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	if len(args) != 1 {
+		return Usagef("Usage: %s &lt;seconds&gt;", cmd)
+	}
+
+	d, err := time.ParseDuration(args[0])
+	if n, e := strconv.Atoi(args[0]); e == nil {
+		d, err = time.Duration(n)*time.Second, nil
+	}
+	if err != nil || d <= 0 || d > maxCountdown {
+		return Usagef("Usage: %s &lt;seconds&gt; (at most %v)", cmd, maxCountdown)
+	}
+
+	e, err := c.addReminder(ctx, ReminderCountdown, true, d, "")
+	if err != nil {
+		return Errorf("Error: %s", html.EscapeString(err.Error()))
+	}
+	return Replyf("Countdown #%v: %v seconds", e.ID, d.Seconds())
+}
+
+// CommandReminders lists or cancels reminders, timers and countdowns.
+func CommandReminders(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	owner := senderIdentity(ctx.Sender)
+
+	if len(args) == 2 && args[0] == "cancel" {
+		id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			return Usagef("Usage: %s cancel &lt;id&gt;", cmd)
+		}
+
+		e := c.reminders.get(id)
+		if e == nil || (e.Owner != owner && (e.User != "" || e.Channel != ctx.ChannelName())) {
+			return Errorf("Error: no reminder #%v", id)
+		}
+
+		c.reminders.remove(id)
+		return Replyf("Cancelled %s #%v", e.Kind, id)
+	}
+	if len(args) != 0 {
+		return Usagef("Usage: %s [cancel &lt;id&gt;]", cmd)
+	}
+
+	list := c.reminders.visible(owner, ctx.ChannelName())
+	if len(list) == 0 {
+		return Reply("No pending reminders")
+	}
+
+	cat := c.catalog(ctx)
+	lines := make([]string, len(list))
+	for i, e := range list {
+		lines[i] = cat.Sprintf("#%v %s at %s", e.ID, cat.Translate(e.Kind), e.Time.Format("2006-01-02 15:04:05"))
+		if e.Text != "" {
+			lines[i] += ": " + html.EscapeString(e.Text)
+		}
+		if e.Channel != "" {
+			lines[i] += cat.Sprintf(" (in %s)", html.EscapeString(e.Channel))
+		}
+		lines[i] = "<li>" + lines[i] + "</li>"
+	}
+	return Replyf("Pending reminders:<ul>%s</ul>", strings.Join(lines, ""))
+}
@@ -0,0 +1,358 @@
+package bot
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockType is the type of a block in a message.
+type BlockType int
+
+// Types of blocks in a message.
+const (
+	BlockParagraph BlockType = iota
+	BlockList
+	BlockOrderedList
+	BlockPre
+)
+
+// Style is the style of a span of text, as a combination of flags.
+type Style int
+
+// Styles of spans of text.
+const (
+	StyleBold Style = 1 << iota
+	StyleItalic
+	StyleCode
+)
+
+// Span is a span of text with a single style.
+// The text of a span is plain text, and is escaped when rendered.
+type Span struct {
+	Text  string
+	Style Style
+	Link  string
+}
+
+// Block is a paragraph, list or preformatted text in a message.
+// Lines contains the lines of a paragraph or preformatted text, or the items of a list.
+type Block struct {
+	Type  BlockType
+	Lines [][]Span
+}
+
+// Message is a formatted message that can be rendered for every platform.
+type Message struct {
+	Blocks []*Block
+}
+
+// htmlToken matches HTML tags, with the closing slash, name and attributes as submatches.
+var htmlToken = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+
+// htmlHref matches the href attribute of an HTML link.
+var htmlHref = regexp.MustCompile(`(?i)href\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// htmlSpace matches whitespace, which is collapsed in HTML.
+var htmlSpace = regexp.MustCompile(`\s+`)
+
+// messageParser contains the state of parsing HTML into a message.
+type messageParser struct {
+	msg                *Message
+	block              *Block
+	bold, italic, code int
+	link               string
+}
+
+// ParseHTML parses the subset of HTML used in Mumble replies into a message.
+// Unknown tags are ignored, and only their content is kept.
+func ParseHTML(s string) *Message {
+	p := &messageParser{msg: new(Message)}
+	for len(s) > 0 {
+		loc := htmlToken.FindStringSubmatchIndex(s)
+		if loc == nil {
+			p.text(s)
+			break
+		}
+		p.text(s[:loc[0]])
+		p.tag(s[loc[2]:loc[3]] == "/", strings.ToLower(s[loc[4]:loc[5]]), s[loc[6]:loc[7]])
+		s = s[loc[1]:]
+	}
+	p.end()
+	return p.msg
+}
+
+// start ends the current block and starts a new block of the given type.
+func (p *messageParser) start(typ BlockType) {
+	p.end()
+	p.block = &Block{Type: typ}
+	p.msg.Blocks = append(p.msg.Blocks, p.block)
+}
+
+// end ends the current block, removing trailing whitespace and empty lines.
+// Empty blocks are removed from the message.
+func (p *messageParser) end() {
+	if p.block == nil {
+		return
+	}
+	if p.block.Type != BlockPre {
+		for i, l := range p.block.Lines {
+			if n := len(l); n > 0 {
+				l[n-1].Text = strings.TrimRight(l[n-1].Text, " ")
+				if l[n-1].Text == "" {
+					p.block.Lines[i] = l[:n-1]
+				}
+			}
+		}
+	}
+	for n := len(p.block.Lines); n > 0 && len(p.block.Lines[n-1]) == 0; n-- {
+		p.block.Lines = p.block.Lines[:n-1]
+	}
+	if len(p.block.Lines) == 0 {
+		p.msg.Blocks = p.msg.Blocks[:len(p.msg.Blocks)-1]
+	}
+	p.block = nil
+}
+
+// newLine starts a new line in the current block, starting a paragraph if needed.
+func (p *messageParser) newLine() {
+	if p.block == nil {
+		p.start(BlockParagraph)
+	}
+	p.block.Lines = append(p.block.Lines, nil)
+}
+
+// line returns the current line, starting one if needed.
+func (p *messageParser) line() *[]Span {
+	if p.block == nil || len(p.block.Lines) == 0 {
+		p.newLine()
+	}
+	return &p.block.Lines[len(p.block.Lines)-1]
+}
+
+// style returns the current style.
+func (p *messageParser) style() (s Style) {
+	if p.bold > 0 {
+		s |= StyleBold
+	}
+	if p.italic > 0 {
+		s |= StyleItalic
+	}
+	if p.code > 0 {
+		s |= StyleCode
+	}
+	return
+}
+
+// text adds HTML text to the current line.
+func (p *messageParser) text(s string) {
+	if s == "" {
+		return
+	}
+
+	if p.block != nil && p.block.Type == BlockPre {
+		for i, l := range strings.Split(html.UnescapeString(s), "\n") {
+			if i > 0 {
+				p.newLine()
+			}
+			if l != "" {
+				line := p.line()
+				*line = append(*line, Span{Text: l})
+			}
+		}
+		return
+	}
+
+	s = html.UnescapeString(htmlSpace.ReplaceAllString(s, " "))
+	if p.block == nil || len(p.block.Lines) == 0 || len(p.block.Lines[len(p.block.Lines)-1]) == 0 {
+		s = strings.TrimLeft(s, " ")
+	}
+	if s == "" {
+		return
+	}
+
+	line := p.line()
+	span := Span{Text: s, Style: p.style(), Link: p.link}
+	if n := len(*line); n > 0 && (*line)[n-1].Style == span.Style && (*line)[n-1].Link == span.Link {
+		(*line)[n-1].Text += span.Text
+		return
+	}
+	*line = append(*line, span)
+}
+
+// tag handles an opening or closing HTML tag.
+func (p *messageParser) tag(closing bool, name, attrs string) {
+	delta := 1
+	if closing {
+		delta = -1
+	}
+
+	switch name {
+	case "b", "strong":
+		p.bold += delta
+	case "i", "em":
+		p.italic += delta
+	case "code", "tt":
+		p.code += delta
+	case "a":
+		p.link = ""
+		if m := htmlHref.FindStringSubmatch(attrs); !closing && m != nil {
+			p.link = html.UnescapeString(m[1] + m[2])
+		}
+	case "br":
+		if p.block != nil && p.block.Type != BlockParagraph && p.block.Type != BlockPre {
+			p.text(" ")
+		} else {
+			p.line()
+			p.newLine()
+		}
+	case "p", "div":
+		p.end()
+	case "ul", "ol":
+		p.end()
+		if !closing {
+			if name == "ol" {
+				p.start(BlockOrderedList)
+			} else {
+				p.start(BlockList)
+			}
+		}
+	case "li":
+		if closing {
+			return
+		}
+		if p.block == nil || (p.block.Type != BlockList && p.block.Type != BlockOrderedList) {
+			p.start(BlockList)
+		}
+		p.newLine()
+	case "pre":
+		p.end()
+		if !closing {
+			p.start(BlockPre)
+		}
+	}
+	if p.bold < 0 || p.italic < 0 || p.code < 0 {
+		p.bold, p.italic, p.code = 0, 0, 0
+	}
+}
+
+// renderSpans renders a line of spans as HTML, using only tags supported by all platforms.
+func renderSpans(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		text := html.EscapeString(s.Text)
+		if s.Style&StyleCode != 0 {
+			text = "<code>" + text + "</code>"
+		}
+		if s.Style&StyleItalic != 0 {
+			text = "<i>" + text + "</i>"
+		}
+		if s.Style&StyleBold != 0 {
+			text = "<b>" + text + "</b>"
+		}
+		if s.Link != "" {
+			text = `<a href="` + html.EscapeString(s.Link) + `">` + text + "</a>"
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// spansText renders a line of spans as plain text.
+// Links are added after their text, unless the text is the link itself.
+func spansText(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.Text)
+		if s.Link != "" && s.Link != s.Text {
+			b.WriteString(" (" + s.Link + ")")
+		}
+	}
+	return b.String()
+}
+
+// preText returns the text of the lines of preformatted text.
+func preText(lines [][]Span) string {
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = spansText(l)
+	}
+	return strings.Join(text, "\n")
+}
+
+// HTML renders the message as HTML, as used by Mumble and
+// in the formatted body (org.matrix.custom.html) of Matrix messages.
+func (m *Message) HTML() string {
+	var b strings.Builder
+	for i, block := range m.Blocks {
+		switch block.Type {
+		case BlockParagraph:
+			if i > 0 && m.Blocks[i-1].Type == BlockParagraph {
+				b.WriteString("<br/>")
+			}
+			for j, l := range block.Lines {
+				if j > 0 {
+					b.WriteString("<br/>")
+				}
+				b.WriteString(renderSpans(l))
+			}
+		case BlockList, BlockOrderedList:
+			tag := "ul"
+			if block.Type == BlockOrderedList {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag + ">")
+			for _, l := range block.Lines {
+				b.WriteString("<li>" + renderSpans(l) + "</li>")
+			}
+			b.WriteString("</" + tag + ">")
+		case BlockPre:
+			b.WriteString("<pre>" + html.EscapeString(preText(block.Lines)) + "</pre>")
+		}
+	}
+	return b.String()
+}
+
+// Telegram renders the message as Telegram HTML.
+// Telegram does not support line break and list tags, so these are rendered as text.
+func (m *Message) Telegram() string {
+	lines := make([]string, 0, len(m.Blocks))
+	for _, block := range m.Blocks {
+		for j, l := range block.Lines {
+			switch block.Type {
+			case BlockParagraph:
+				lines = append(lines, renderSpans(l))
+			case BlockList:
+				lines = append(lines, "• "+renderSpans(l))
+			case BlockOrderedList:
+				lines = append(lines, strconv.Itoa(j+1)+". "+renderSpans(l))
+			}
+		}
+		if block.Type == BlockPre {
+			lines = append(lines, "<pre>"+html.EscapeString(preText(block.Lines))+"</pre>")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Text renders the message as plain text.
+func (m *Message) Text() string {
+	lines := make([]string, 0, len(m.Blocks))
+	for _, block := range m.Blocks {
+		for j, l := range block.Lines {
+			switch block.Type {
+			case BlockParagraph:
+				lines = append(lines, strings.TrimSpace(spansText(l)))
+			case BlockList:
+				lines = append(lines, "- "+strings.TrimSpace(spansText(l)))
+			case BlockOrderedList:
+				lines = append(lines, strconv.Itoa(j+1)+". "+strings.TrimSpace(spansText(l)))
+			}
+		}
+		if block.Type == BlockPre {
+			lines = append(lines, preText(block.Lines))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
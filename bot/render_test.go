@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		in     string
+		blocks []*Block
+	}{
+		{``, nil},
+		{`Hello <b>world</b>`, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "Hello "}, {Text: "world", Style: StyleBold}}}},
+		}},
+		{`a<br/>b`, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "a"}}, {{Text: "b"}}}},
+		}},
+		{`<ul><li>one</li><li>two</li></ul>`, []*Block{
+			{Type: BlockList, Lines: [][]Span{{{Text: "one"}}, {{Text: "two"}}}},
+		}},
+		{`<pre>x &lt; y` + "\n" + `  z</pre>`, []*Block{
+			{Type: BlockPre, Lines: [][]Span{{{Text: "x < y"}}, {{Text: "  z"}}}},
+		}},
+		{`<a href="https://example.com/?a=1&amp;b=2">link</a>`, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "link", Link: "https://example.com/?a=1&b=2"}}}},
+		}},
+		{`<B><I>x</I></B> <span>y</span>`, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "x", Style: StyleBold | StyleItalic}, {Text: " y"}}}},
+		}},
+		{`  a   b  `, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "a b"}}}},
+		}},
+		{`</b>x<p></p>`, []*Block{
+			{Type: BlockParagraph, Lines: [][]Span{{{Text: "x"}}}},
+		}},
+	}
+
+	for _, tt := range tests {
+		if msg := ParseHTML(tt.in); !reflect.DeepEqual(msg.Blocks, tt.blocks) {
+			t.Errorf("ParseHTML(%q) = %s, expected %s", tt.in, formatBlocks(msg.Blocks), formatBlocks(tt.blocks))
+		}
+	}
+}
+
+// formatBlocks formats blocks for test failures.
+func formatBlocks(blocks []*Block) string {
+	s := make([]string, len(blocks))
+	for i, b := range blocks {
+		s[i] = fmt.Sprintf("%+v", *b)
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		in                   string
+		html, telegram, text string
+	}{
+		{
+			`Hello <b>world</b>`,
+			`Hello <b>world</b>`, `Hello <b>world</b>`, `Hello world`,
+		},
+		{
+			`a<br/>b`,
+			`a<br/>b`, "a\nb", "a\nb",
+		},
+		{
+			`Intro<p>para</p>`,
+			`Intro<br/>para`, "Intro\npara", "Intro\npara",
+		},
+		{
+			`Items:<ul><li>one</li><li><code>two</code></li></ul>`,
+			`Items:<ul><li>one</li><li><code>two</code></li></ul>`, "Items:\n• one\n• <code>two</code>", "Items:\n- one\n- two",
+		},
+		{
+			`<ol><li>a</li><li>b</li></ol>`,
+			`<ol><li>a</li><li>b</li></ol>`, "1. a\n2. b", "1. a\n2. b",
+		},
+		{
+			`<pre>x &lt; y` + "\n" + `  z</pre>`,
+			"<pre>x &lt; y\n  z</pre>", "<pre>x &lt; y\n  z</pre>", "x < y\n  z",
+		},
+		{
+			`<a href="https://example.com/?a=1&amp;b=2">link</a>`,
+			`<a href="https://example.com/?a=1&amp;b=2">link</a>`,
+			`<a href="https://example.com/?a=1&amp;b=2">link</a>`,
+			`link (https://example.com/?a=1&b=2)`,
+		},
+		{
+			`<a href="https://example.com">https://example.com</a>`,
+			`<a href="https://example.com">https://example.com</a>`,
+			`<a href="https://example.com">https://example.com</a>`,
+			`https://example.com`,
+		},
+		{
+			`&lt;script&gt; &amp; <i>more</i>`,
+			`&lt;script&gt; &amp; <i>more</i>`, `&lt;script&gt; &amp; <i>more</i>`, `<script> & more`,
+		},
+		{
+			`<img src="x.png">caption`,
+			`caption`, `caption`, `caption`,
+		},
+	}
+
+	for _, tt := range tests {
+		msg := ParseHTML(tt.in)
+		if s := msg.HTML(); s != tt.html {
+			t.Errorf("ParseHTML(%q).HTML() = %q, expected %q", tt.in, s, tt.html)
+		}
+		if s := msg.Telegram(); s != tt.telegram {
+			t.Errorf("ParseHTML(%q).Telegram() = %q, expected %q", tt.in, s, tt.telegram)
+		}
+		if s := msg.Text(); s != tt.text {
+			t.Errorf("ParseHTML(%q).Text() = %q, expected %q", tt.in, s, tt.text)
+		}
+	}
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		`Hello <b>world</b>`,
+		`a<br/>b<ul><li>one</li></ul><pre>code</pre>`,
+		`<a href="https://example.com/?a=1&amp;b=2"><b>link</b></a> &lt;tag&gt;`,
+	} {
+		html := ParseHTML(in).HTML()
+		if again := ParseHTML(html).HTML(); again != html {
+			t.Errorf("rendering %q again = %q, expected %q", in, again, html)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+)
+
+// Status is the status of the result of a command.
+type Status string
+
+// Statuses of command results.
+const (
+	StatusOK          Status = "ok"
+	StatusError       Status = "error"
+	StatusInvalid     Status = "invalid"
+	StatusDenied      Status = "denied"
+	StatusRateLimited Status = "rate_limited"
+	StatusUnknown     Status = "unknown"
+)
+
+// Attachment types.
+const (
+	AttachmentSound   = "sound"
+	AttachmentSticker = "sticker"
+)
+
+// Attachment represents media related to a command result,
+// such as a played sound or a sent sticker.
+type Attachment struct {
+	Type string
+	Name string
+}
+
+// Result represents the result of a command.
+// The HTML is sent to Mumble, and the Text is used by platforms that do not support HTML.
+// If the Text is empty, it is derived from the HTML.
+// The format and arguments of the reply are kept to translate it for the recipient.
+type Result struct {
+	Status      Status
+	Text        string
+	HTML        string
+	Attachments []Attachment
+
+	format  string
+	args    []interface{}
+	literal bool
+	route   string
+}
+
+// Reply returns a successful result with an HTML reply.
+func Reply(html string) *Result {
+	return &Result{Status: StatusOK, HTML: html, format: html, literal: true}
+}
+
+// Replyf returns a successful result with a formatted HTML reply.
+func Replyf(format string, a ...interface{}) *Result {
+	return newResult(StatusOK, format, a...)
+}
+
+// Errorf returns a failed result with a formatted HTML reply.
+func Errorf(format string, a ...interface{}) *Result {
+	return newResult(StatusError, format, a...)
+}
+
+// Usagef returns a result for invalid usage of a command with a formatted HTML reply.
+func Usagef(format string, a ...interface{}) *Result {
+	return newResult(StatusInvalid, format, a...)
+}
+
+// newResult returns a result with a formatted HTML reply.
+func newResult(status Status, format string, a ...interface{}) *Result {
+	return &Result{Status: status, HTML: fmt.Sprintf(format, a...), format: format, args: a}
+}
+
+// localize returns the result with the reply translated using a catalog.
+// The result is returned as-is if its reply has no translation,
+// or if the reply was changed after the result was created.
+func (r *Result) localize(cat Catalog) *Result {
+	if r == nil || r.format == "" || r.HTML != r.original() {
+		return r
+	}
+	t, ok := cat[r.format]
+	if !ok {
+		return r
+	}
+
+	l := *r
+	if r.literal {
+		l.HTML = t
+	} else {
+		l.HTML = fmt.Sprintf(t, r.args...)
+	}
+	return &l
+}
+
+// original returns the untranslated HTML reply of the result.
+func (r *Result) original() string {
+	if r.literal {
+		return r.format
+	}
+	return fmt.Sprintf(r.format, r.args...)
+}
+
+// Attach adds an attachment to the result, and returns the result.
+func (r *Result) Attach(typ, name string) *Result {
+	r.Attachments = append(r.Attachments, Attachment{Type: typ, Name: name})
+	return r
+}
+
+// OK returns true if the command was successful.
+func (r *Result) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Empty returns true if the result contains no reply.
+func (r *Result) Empty() bool {
+	return r.HTML == "" && r.Text == ""
+}
+
+// Message returns the reply as a message that can be rendered for every platform.
+func (r *Result) Message() *Message {
+	if r.HTML == "" {
+		return ParseHTML(html.EscapeString(r.Text))
+	}
+	return ParseHTML(r.HTML)
+}
+
+// PlainText returns the reply as plain text.
+func (r *Result) PlainText() string {
+	if r.Text != "" {
+		return r.Text
+	}
+	return r.Message().Text()
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRollHistory is the maximum number of rolls that are kept per user.
+const maxRollHistory = 20
+
+// roll represents a dice roll in the roll history.
+type roll struct {
+	Time   time.Time
+	Result string
+}
+
+// rollHistory contains the most recent rolls of every user by name.
+type rollHistory struct {
+	sync.Mutex
+	rolls map[string][]roll
+}
+
+// add adds a roll to the history of a user.
+func (h *rollHistory) add(user, result string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.rolls == nil {
+		h.rolls = make(map[string][]roll)
+	}
+
+	key := strings.ToLower(user)
+	rolls := append(h.rolls[key], roll{Time: time.Now(), Result: result})
+	if len(rolls) > maxRollHistory {
+		rolls = rolls[len(rolls)-maxRollHistory:]
+	}
+	h.rolls[key] = rolls
+}
+
+// get returns the rolls of a user.
+func (h *rollHistory) get(user string) []roll {
+	h.Lock()
+	defer h.Unlock()
+	return h.rolls[strings.ToLower(user)]
+}
+
+// CommandRolls shows the recent dice rolls of the sender or a given user.
+func CommandRolls(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	var user string
+	switch {
+	case len(args) > 0:
+		user = strings.Join(args, " ")
+	case ctx.Sender != nil:
+		user = ctx.Sender.Name
+	default:
+		return Usagef("Usage: %s &lt;user&gt;", cmd)
+	}
+
+	rolls := c.rolls.get(user)
+	if len(rolls) == 0 {
+		return Replyf("No rolls by %s", html.EscapeString(user))
+	}
+
+	lines := make([]string, len(rolls))
+	for i, r := range rolls {
+		lines[i] = fmt.Sprintf("<li>%s %s</li>", r.Time.Format("15:04"), r.Result)
+	}
+	return Replyf("Recent rolls by %s:<ul>%s</ul>", html.EscapeString(user), strings.Join(lines, ""))
+}
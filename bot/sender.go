@@ -0,0 +1,64 @@
+package bot
+
+import "layeh.com/gumble/gumble"
+
+// Sender represents the issuer of a command.
+type Sender struct {
+	// Name of the Mumble user or API token.
+	Name string
+
+	// Hash of the certificate of the Mumble user.
+	Hash string
+
+	// Registered is true if the Mumble user is registered.
+	Registered bool
+
+	// User is the Mumble user, or nil if the command was not issued by a Mumble user.
+	User *gumble.User
+
+	// Token is the name of the API token used, if any.
+	Token string
+
+	// Chat is the ID of the Matrix or Telegram user, if any.
+	Chat string
+}
+
+// MumbleSender returns the Sender for a Mumble user.
+func MumbleSender(u *gumble.User) *Sender {
+	if u == nil {
+		return nil
+	}
+
+	return &Sender{
+		Name:       u.Name,
+		Hash:       u.Hash,
+		Registered: u.IsRegistered(),
+		User:       u,
+	}
+}
+
+// APISender returns the Sender for an API token.
+func APISender(token string) *Sender {
+	return &Sender{Name: token, Token: token}
+}
+
+// ChatSender returns the Sender for a Matrix or Telegram user,
+// identified by their user ID and shown by their name.
+func ChatSender(id, name string) *Sender {
+	return &Sender{Name: name, Chat: id}
+}
+
+// senderIdentity returns a string identifying a sender,
+// which is their certificate hash or chat user ID if available, or their name otherwise.
+func senderIdentity(s *Sender) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Hash != "":
+		return s.Hash
+	case s.Chat != "":
+		return s.Chat
+	default:
+		return s.Name
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+const (
+	// waitCommand is the command that pauses a sequence of commands.
+	waitCommand = "wait"
+
+	// maxWait is the maximum time a sequence waits in a single step.
+	maxWait = 10 * time.Minute
+)
+
+// HandleSequence handles a sequence of commands separated by semicolons,
+// such as `play drumroll; wait 2s; sticker tada; play applause`.
+// The `wait` command pauses the sequence for a given duration,
+// or until any playing audio has finished when no duration is given.
+//
+// A single command is handled immediately and its response is returned.
+// Sequences are executed in the background, with responses sent to the reply target of the context.
+func (c *Client) HandleSequence(ctx *Context, s string) *Result {
+	return c.handleSequence(ctx, s, nil)
+}
+
+// handleSequence handles a sequence of commands,
+// resulting from the expansion of the given chain of aliases.
+func (c *Client) handleSequence(ctx *Context, s string, aliases []string) *Result {
+	commands, err := splitCommands(s)
+	if err != nil {
+		return Usagef("Error: %s", html.EscapeString(err.Error()))
+	}
+
+	switch len(commands) {
+	case 0:
+		return Reply("")
+	case 1:
+		return c.handleCommand(ctx, commands[0], aliases)
+	}
+
+	for _, cmd := range commands {
+		if _, err := parseWait(cmd); err != nil {
+			return Usagef("Error: %s", html.EscapeString(err.Error()))
+		}
+	}
+
+	go c.runSequence(ctx, commands, aliases)
+	return Reply("")
+}
+
+// runSequence executes a sequence of commands.
+func (c *Client) runSequence(ctx *Context, commands []string, aliases []string) {
+	for _, cmd := range commands {
+		wait, _ := parseWait(cmd)
+		switch {
+		case wait > 0:
+			time.Sleep(wait)
+		case wait < 0:
+			c.waitForAudio()
+		default:
+			c.SendReply(ctx, c.handleCommand(ctx, cmd, aliases))
+		}
+	}
+}
+
+// waitForAudio waits for any started audio to finish.
+func (c *Client) waitForAudio() {
+	// Give audio started by the previous command a moment to be queued.
+	time.Sleep(audioPollInterval)
+	c.WaitAudio(maxWait)
+}
+
+// parseWait parses a wait command.
+// It returns the duration to wait, -1 to wait for audio to finish,
+// or 0 if the command is not a wait command.
+func parseWait(s string) (time.Duration, error) {
+	cmd, args, err := parseCommand(s)
+	if err != nil || cmd != waitCommand {
+		return 0, err
+	}
+
+	switch len(args) {
+	case 0:
+		return -1, nil
+	case 1:
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 || d > maxWait {
+			return 0, fmt.Errorf("invalid wait duration %q, expected a duration up to %v", args[0], maxWait)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("usage: %s [duration]", waitCommand)
+	}
+}
@@ -0,0 +1,430 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// StarlarkExtension contains the filename extension of Starlark scripts.
+const StarlarkExtension = ".star"
+
+// starlarkCommand is a command defined in a Starlark script.
+type starlarkCommand struct {
+	name string
+	fn   starlark.Callable
+}
+
+// starlarkHooks contains the hook handlers defined in Starlark scripts.
+type starlarkHooks map[string][]starlark.Callable
+
+// starlarkInvocation contains the state of a single call of a Starlark function.
+// The chain contains the Starlark commands and aliases that resulted in the call.
+type starlarkInvocation struct {
+	client  *Client
+	ctx     *Context
+	chain   []string
+	replies []string
+}
+
+// starlarkLoader collects the commands and hooks defined while loading Starlark scripts.
+type starlarkLoader struct {
+	commands []*Command
+	names    []string
+	hooks    starlarkHooks
+}
+
+// starlarkBuiltins contains the functions available to Starlark scripts.
+var starlarkBuiltins = starlark.StringDict{
+	"command":    starlark.NewBuiltin("command", starlarkDefineCommand),
+	"hook":       starlark.NewBuiltin("hook", starlarkDefineHook),
+	"reply":      starlark.NewBuiltin("reply", starlarkReply),
+	"play":       starlark.NewBuiltin("play", starlarkPlay),
+	"stop":       starlark.NewBuiltin("stop", starlarkStop),
+	"volume":     starlark.NewBuiltin("volume", starlarkVolume),
+	"set_volume": starlark.NewBuiltin("set_volume", starlarkSetVolume),
+	"sticker":    starlark.NewBuiltin("sticker", starlarkSticker),
+	"users":      starlark.NewBuiltin("users", starlarkUsers),
+	"execute":    starlark.NewBuiltin("execute", starlarkExecute),
+}
+
+// LoadStarlark (re)loads the commands and hooks defined in the Starlark scripts
+// in the configured directory.
+// Scripts define commands and hooks using the following functions:
+//
+//	command(name, fn, summary="", args=[], examples=[])
+//	hook(event, fn)
+//
+// Command functions are called as fn(sender, args), and hook functions as fn(sender).
+// The sender is a struct with the name, hash, registered and channel fields.
+// Commands can reply by returning a string or by calling reply(text, html=False).
+// Both commands and hooks can call the following functions:
+//
+//	play(name, loop=False), stop()
+//	volume(), set_volume(db)
+//	sticker(name)
+//	users()
+//	execute(command)
+//
+// Commands with the same name as an existing command are ignored.
+// Scripts, commands and hooks are stopped when they exceed the configured timeout.
+func (c *Client) LoadStarlark() error {
+	c.Lock()
+	for _, name := range c.starlark {
+		delete(c.commands, name)
+	}
+	c.starlark = nil
+	c.starlarkHooks = nil
+	c.Unlock()
+
+	dir := c.Config.Mumble.Starlark.Directory
+	if dir == "" {
+		return nil
+	}
+
+	files, err := listFiles(dir, StarlarkExtension)
+	if err != nil {
+		return err
+	}
+
+	loader := &starlarkLoader{hooks: make(starlarkHooks)}
+	for _, name := range files {
+		path := filepath.Join(dir, name+StarlarkExtension)
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		thread := c.starlarkThread(name, nil)
+		thread.SetLocal("loader", loader)
+		stop := c.limitStarlark(thread)
+		if _, err := starlark.ExecFile(thread, path, src, starlarkBuiltins); err != nil {
+			log.Printf("Error loading Starlark script %q: %s", path, err)
+		}
+		stop()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	for i, cmd := range loader.commands {
+		name := loader.names[i]
+		if _, ok := c.commands[name]; ok {
+			log.Printf("Ignoring existing command %q defined in Starlark", name)
+			continue
+		}
+		c.commands[name] = cmd
+		c.starlark = append(c.starlark, name)
+	}
+	c.starlarkHooks = loader.hooks
+
+	return nil
+}
+
+// starlarkThread returns a thread for calling Starlark functions.
+func (c *Client) starlarkThread(name string, inv *starlarkInvocation) *starlark.Thread {
+	if inv == nil {
+		inv = &starlarkInvocation{client: c, ctx: c.NewContext(SourceInternal, nil)}
+	}
+
+	thread := &starlark.Thread{
+		Name:  name,
+		Print: func(_ *starlark.Thread, msg string) { log.Printf("Starlark %s: %s", name, msg) },
+	}
+	thread.SetLocal("invocation", inv)
+	return thread
+}
+
+// limitStarlark cancels a Starlark thread when it exceeds the configured timeout.
+// The returned function stops the timer, and must be called when the thread is done.
+func (c *Client) limitStarlark(thread *starlark.Thread) (stop func() bool) {
+	timeout := c.Config.Mumble.Starlark.Timeout
+	timer := time.AfterFunc(timeout, func() {
+		thread.Cancel(fmt.Sprintf("timed out after %v", timeout))
+	})
+	return timer.Stop
+}
+
+// callStarlark calls a Starlark function, cancelling it when it exceeds the configured timeout.
+func (c *Client) callStarlark(thread *starlark.Thread, fn starlark.Callable, args starlark.Tuple) (starlark.Value, error) {
+	stop := c.limitStarlark(thread)
+	defer stop()
+	return starlark.Call(thread, fn, args, nil)
+}
+
+// starlarkHandler returns the handler of a command defined in Starlark.
+// Commands executing themselves, directly or through other commands and aliases, are rejected.
+func starlarkHandler(fn starlark.Callable) CommandHandler {
+	return func(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+		if resp := checkChain(ctx.chain, cmd); resp != nil {
+			return resp
+		}
+
+		list := make([]starlark.Value, len(args))
+		for i, a := range args {
+			list[i] = starlark.String(a)
+		}
+
+		inv := &starlarkInvocation{client: c, ctx: ctx, chain: extendChain(ctx.chain, cmd)}
+		thread := c.starlarkThread(cmd, inv)
+		res, err := c.callStarlark(thread, fn, starlark.Tuple{starlarkSender(ctx), starlark.NewList(list)})
+		if err != nil {
+			return Errorf("Error: %s", html.EscapeString(err.Error()))
+		}
+		if str, ok := res.(starlark.String); ok && str != "" {
+			inv.replies = append(inv.replies, html.EscapeString(string(str)))
+		}
+
+		return Reply(strings.Join(inv.replies, "<br/>"))
+	}
+}
+
+// starlarkQueueSize is the number of hooks that can wait to be executed.
+const starlarkQueueSize = 64
+
+// starlarkHookCall is a hook waiting to be executed by its Starlark handlers.
+type starlarkHookCall struct {
+	name string
+	ctx  *Context
+}
+
+// queueStarlarkHooks queues the execution of the Starlark handlers of a hook,
+// so that slow handlers do not block the caller.
+// The hook is dropped if too many hooks are waiting.
+func (c *Client) queueStarlarkHooks(name string, ctx *Context) {
+	select {
+	case c.starlarkQueue <- &starlarkHookCall{name: name, ctx: ctx}:
+	default:
+		log.Printf("Dropping Starlark hook %q: too many hooks waiting", name)
+	}
+}
+
+// runStarlarkHooks executes the queued hooks in order.
+func (c *Client) runStarlarkHooks() {
+	for call := range c.starlarkQueue {
+		c.executeStarlarkHooks(call.name, call.ctx)
+	}
+}
+
+// executeStarlarkHooks calls the Starlark handlers of a hook.
+// Any replies are sent to the reply target of the context.
+func (c *Client) executeStarlarkHooks(name string, ctx *Context) {
+	c.Lock()
+	hooks := c.starlarkHooks[name]
+	c.Unlock()
+
+	for _, fn := range hooks {
+		inv := &starlarkInvocation{client: c, ctx: ctx, chain: ctx.chain}
+		thread := c.starlarkThread(name, inv)
+		if _, err := c.callStarlark(thread, fn, starlark.Tuple{starlarkSender(ctx)}); err != nil {
+			log.Printf("Error executing Starlark hook %q: %s", name, err)
+		}
+		if len(inv.replies) > 0 {
+			c.SendReply(ctx, Reply(strings.Join(inv.replies, "<br/>")))
+		}
+	}
+}
+
+// starlarkSender converts the sender of a context to a Starlark struct.
+func starlarkSender(ctx *Context) starlark.Value {
+	d := starlark.StringDict{
+		"name":       starlark.String(""),
+		"hash":       starlark.String(""),
+		"registered": starlark.False,
+		"channel":    starlark.String(ctx.ChannelName()),
+	}
+	if s := ctx.Sender; s != nil {
+		d["name"] = starlark.String(s.Name)
+		d["hash"] = starlark.String(s.Hash)
+		d["registered"] = starlark.Bool(s.Registered)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, d)
+}
+
+// invocation returns the invocation of the current thread.
+func invocation(thread *starlark.Thread) *starlarkInvocation {
+	return thread.Local("invocation").(*starlarkInvocation)
+}
+
+// starlarkDefineCommand implements the `command` Starlark function.
+func starlarkDefineCommand(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	loader, ok := thread.Local("loader").(*starlarkLoader)
+	if !ok {
+		return nil, fmt.Errorf("%s: commands can only be defined while loading", b.Name())
+	}
+
+	var name, summary string
+	var fn starlark.Callable
+	var arguments, examples *starlark.List
+	err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"name", &name, "fn", &fn, "summary?", &summary, "args?", &arguments, "examples?", &examples)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &Command{Summary: summary, Handler: starlarkHandler(fn)}
+	for _, a := range starlarkStrings(arguments) {
+		arg := Argument{Name: strings.Trim(a, "[]")}
+		arg.Optional = arg.Name != a
+		cmd.Args = append(cmd.Args, arg)
+	}
+	cmd.Examples = starlarkStrings(examples)
+
+	loader.commands = append(loader.commands, cmd)
+	loader.names = append(loader.names, name)
+	return starlark.None, nil
+}
+
+// starlarkDefineHook implements the `hook` Starlark function.
+func starlarkDefineHook(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	loader, ok := thread.Local("loader").(*starlarkLoader)
+	if !ok {
+		return nil, fmt.Errorf("%s: hooks can only be defined while loading", b.Name())
+	}
+
+	var event string
+	var fn starlark.Callable
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "event", &event, "fn", &fn); err != nil {
+		return nil, err
+	}
+
+	loader.hooks[event] = append(loader.hooks[event], fn)
+	return starlark.None, nil
+}
+
+// starlarkReply implements the `reply` Starlark function.
+func starlarkReply(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var text string
+	var isHTML bool
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "text", &text, "html?", &isHTML); err != nil {
+		return nil, err
+	}
+
+	if !isHTML {
+		text = html.EscapeString(text)
+	}
+
+	inv := invocation(thread)
+	inv.replies = append(inv.replies, text)
+	return starlark.None, nil
+}
+
+// starlarkPlay implements the `play` Starlark function.
+func starlarkPlay(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var loop bool
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "loop?", &loop); err != nil {
+		return nil, err
+	}
+
+	_, err := invocation(thread).client.performPluginAction(&PluginAction{Type: "play", File: name, Loop: loop})
+	return starlark.None, err
+}
+
+// starlarkStop implements the `stop` Starlark function.
+func starlarkStop(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+
+	invocation(thread).client.Mumble.StopAudio()
+	return starlark.None, nil
+}
+
+// starlarkVolume implements the `volume` Starlark function.
+func starlarkVolume(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+
+	return starlark.MakeInt(int(invocation(thread).client.Volume())), nil
+}
+
+// starlarkSetVolume implements the `set_volume` Starlark function.
+func starlarkSetVolume(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var volume int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "db", &volume); err != nil {
+		return nil, err
+	}
+	if volume < MinVolume || volume > MaxVolume {
+		return nil, fmt.Errorf("%s: volume must be between %v and %v", b.Name(), MinVolume, MaxVolume)
+	}
+
+	invocation(thread).client.SetVolume(int8(volume))
+	return starlark.None, nil
+}
+
+// starlarkSticker implements the `sticker` Starlark function.
+func starlarkSticker(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+
+	return starlark.None, invocation(thread).client.SendSticker(name)
+}
+
+// starlarkUsers implements the `users` Starlark function.
+func starlarkUsers(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+
+	c := invocation(thread).client
+	users := make([]starlark.Value, 0, len(c.Mumble.Users))
+	for _, u := range c.Mumble.Users {
+		if u == c.Mumble.Self {
+			continue
+		}
+		d := starlark.StringDict{
+			"name":       starlark.String(u.Name),
+			"channel":    starlark.String(""),
+			"registered": starlark.Bool(u.IsRegistered()),
+			"muted":      starlark.Bool(u.Muted || u.SelfMuted),
+			"deafened":   starlark.Bool(u.Deafened || u.SelfDeafened),
+		}
+		if u.Channel != nil {
+			d["channel"] = starlark.String(u.Channel.Name)
+		}
+		users = append(users, starlarkstruct.FromStringDict(starlarkstruct.Default, d))
+	}
+
+	return starlark.NewList(users), nil
+}
+
+// starlarkExecute implements the `execute` Starlark function.
+// The command is executed in the context of the current invocation,
+// extended with the chain of Starlark commands and aliases to detect loops.
+func starlarkExecute(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var command string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "command", &command); err != nil {
+		return nil, err
+	}
+
+	inv := invocation(thread)
+	ctx := *inv.ctx
+	ctx.chain = inv.chain
+	resp := inv.client.handleCommand(&ctx, command, inv.chain).localize(inv.client.catalog(&ctx))
+	return starlark.String(resp.HTML), nil
+}
+
+// starlarkStrings converts a Starlark list to a list of strings.
+func starlarkStrings(list *starlark.List) []string {
+	if list == nil {
+		return nil
+	}
+
+	strs := make([]string, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		if s, ok := starlark.AsString(list.Index(i)); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"html"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// moveSubject is the permission subject for moving other users.
+const moveSubject = "move"
+
+// teamMove represents a user that was moved into a team channel.
+type teamMove struct {
+	user    *gumble.User
+	channel *gumble.Channel
+}
+
+// splitTeams randomly partitions users into n teams,
+// with team sizes that differ by at most one.
+func splitTeams(users []*gumble.User, n int) [][]*gumble.User {
+	shuffled := make([]*gumble.User, len(users))
+	copy(shuffled, users)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	teams := make([][]*gumble.User, n)
+	for i, u := range shuffled {
+		teams[i%n] = append(teams[i%n], u)
+	}
+	return teams
+}
+
+// channelUsers returns the users in a channel, except the bot, sorted by name.
+func (c *Client) channelUsers(channel *gumble.Channel) (users []*gumble.User) {
+	for _, u := range channel.Users {
+		if u != c.Mumble.Self {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return
+}
+
+// checkMove returns an error if the bot is known to be unable to move users into a channel.
+func checkMove(channel *gumble.Channel) error {
+	return checkChannelPermission(channel, gumble.PermissionMove, "move users into")
+}
+
+// CommandTeams splits the users in the channel of the context into random teams,
+// and optionally moves the teams into the given channels.
+func CommandTeams(c *Client, ctx *Context, cmd string, args ...string) (resp *Result) {
+	usage := Usagef("Usage: %s &lt;number&gt; [channel...] | undo", cmd)
+	if len(args) == 0 {
+		return usage
+	}
+	if len(args) == 1 && args[0] == "undo" {
+		return c.undoTeams(ctx)
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 2 {
+		return usage
+	}
+
+	if ctx.Channel == nil {
+		return Errorf("Error: teams can only be made in a channel")
+	}
+	users := c.channelUsers(ctx.Channel)
+	if len(users) < n {
+		return Errorf("Error: not enough users for %v teams", n)
+	}
+
+	// Look up the team channels
+	var channels []*gumble.Channel
+	if names := args[1:]; len(names) > 0 {
+		if len(names) != n {
+			return Errorf("Error: %v channels are needed for %v teams", n, n)
+		}
+		if err := c.checkPermission(ctx, moveSubject); err != nil {
+			return Errorf("Permission denied: %s", html.EscapeString(err.Error()))
+		}
+		for _, name := range names {
+			ch := c.Mumble.FindChannel(name)
+			if ch == nil {
+				return Errorf("Error: unknown channel %q", html.EscapeString(name))
+			}
+			if err := checkMove(ch); err != nil {
+				return Errorf("Error: %s", html.EscapeString(err.Error()))
+			}
+			channels = append(channels, ch)
+		}
+	}
+
+	// Announce and move the teams
+	teams := splitTeams(users, n)
+	cat := c.catalog(ctx)
+	lines := make([]string, n)
+	var moves []teamMove
+	for i, team := range teams {
+		names := make([]string, len(team))
+		for j, u := range team {
+			names[j] = html.EscapeString(u.Name)
+			if channels != nil {
+				moves = append(moves, teamMove{user: u, channel: u.Channel})
+				u.Move(channels[i])
+			}
+		}
+
+		if channels != nil {
+			lines[i] = cat.Sprintf("Team %v (%s): %s", i+1, html.EscapeString(channels[i].Name), strings.Join(names, ", "))
+		} else {
+			lines[i] = cat.Sprintf("Team %v: %s", i+1, strings.Join(names, ", "))
+		}
+		lines[i] = "<li>" + lines[i] + "</li>"
+	}
+
+	if moves != nil {
+		c.Lock()
+		c.teamMoves = moves
+		c.Unlock()
+	}
+
+	return Replyf("Teams:<ul>%s</ul>", strings.Join(lines, ""))
+}
+
+// undoTeams moves the users that were moved into teams back to their original channel.
+func (c *Client) undoTeams(ctx *Context) *Result {
+	if err := c.checkPermission(ctx, moveSubject); err != nil {
+		return Errorf("Permission denied: %s", html.EscapeString(err.Error()))
+	}
+
+	c.Lock()
+	moves := c.teamMoves
+	c.teamMoves = nil
+	c.Unlock()
+
+	if len(moves) == 0 {
+		return Errorf("Error: there are no teams to undo")
+	}
+
+	n := 0
+	for _, m := range moves {
+		if c.Mumble.Users[m.user.Session] == m.user && m.user.Channel != m.channel {
+			m.user.Move(m.channel)
+			n++
+		}
+	}
+	return Replyf(plural(n, "Moved %v user back", "Moved %v users back"), n)
+}
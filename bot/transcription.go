@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/silkeh/mumble_bot/transcribe"
+	"layeh.com/gumble/gumble"
+)
+
+// transcriptCommand is the name of the command, and permission subject, for transcripts.
+const transcriptCommand = "transcript"
+
+const (
+	// minUtterance is the minimum duration of audio that is transcribed.
+	minUtterance = 500 * time.Millisecond
+
+	// maxUtterance is the maximum duration of audio that is transcribed at once.
+	maxUtterance = 30 * time.Second
+)
+
+// Transcript represents a single transcribed utterance.
+type Transcript struct {
+	Time time.Time
+	User string
+	Text string
+}
+
+// utterance contains the audio of a user that is currently speaking.
+type utterance struct {
+	user  *gumble.User
+	start time.Time
+	pcm   []int16
+	timer *time.Timer
+}
+
+// transcription collects the audio of individual users and transcribes it.
+// It implements mumble.AudioHandler.
+type transcription struct {
+	sync.Mutex
+	client      *Client
+	config      *TranscriptionConfig
+	transcriber transcribe.Transcriber
+	active      bool
+	utterances  map[uint32]*utterance
+	transcripts []*Transcript
+}
+
+// newTranscription returns a transcription for the given configuration.
+func newTranscription(c *Client, config *TranscriptionConfig) *transcription {
+	return &transcription{
+		client:      c,
+		config:      config,
+		transcriber: transcribe.NewHTTP(config.URL, config.Fields, config.Timeout),
+		utterances:  make(map[uint32]*utterance),
+	}
+}
+
+// HandleAudio stores the audio of a user until they stop speaking.
+func (t *transcription) HandleAudio(user *gumble.User, pcm []int16) {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.active {
+		return
+	}
+
+	u, ok := t.utterances[user.Session]
+	if !ok {
+		u = &utterance{user: user, start: time.Now()}
+		u.timer = time.AfterFunc(t.config.Silence, func() { t.flush(u) })
+		t.utterances[user.Session] = u
+	} else {
+		u.timer.Reset(t.config.Silence)
+	}
+
+	u.pcm = append(u.pcm, pcm...)
+	if len(u.pcm) >= int(maxUtterance*gumble.AudioSampleRate/time.Second) {
+		u.timer.Stop()
+		delete(t.utterances, user.Session)
+		go t.transcribe(u)
+	}
+}
+
+// flush transcribes an utterance after its user stopped speaking.
+// Utterances that were already removed, because they were flushed or
+// transcription was stopped, are ignored.
+func (t *transcription) flush(u *utterance) {
+	t.Lock()
+	if t.utterances[u.user.Session] != u {
+		t.Unlock()
+		return
+	}
+	delete(t.utterances, u.user.Session)
+	t.Unlock()
+
+	t.transcribe(u)
+}
+
+// transcribe transcribes a finished utterance, which must no longer be in the utterances.
+func (t *transcription) transcribe(u *utterance) {
+	if len(u.pcm) < int(minUtterance*gumble.AudioSampleRate/time.Second) {
+		return
+	}
+
+	text, err := t.transcriber.Transcribe(u.pcm)
+	if err != nil {
+		log.Printf("Error transcribing audio of %q: %s", u.user.Name, err)
+		return
+	}
+	if text == "" {
+		return
+	}
+
+	t.add(&Transcript{Time: u.start, User: u.user.Name, Text: text})
+	if t.config.Forward {
+		if err := t.client.SendText(fmt.Sprintf("%s: %s", u.user.Name, text)); err != nil {
+			log.Printf("Error forwarding transcript: %s", err)
+		}
+	}
+}
+
+// add stores a transcript in chronological order,
+// removing the oldest transcripts when more than the maximum are stored.
+// Transcripts mostly arrive in order, so the position is searched from the end.
+func (t *transcription) add(tr *Transcript) {
+	t.Lock()
+	defer t.Unlock()
+
+	i := len(t.transcripts)
+	for i > 0 && tr.Time.Before(t.transcripts[i-1].Time) {
+		i--
+	}
+	t.transcripts = append(t.transcripts, nil)
+	copy(t.transcripts[i+1:], t.transcripts[i:])
+	t.transcripts[i] = tr
+
+	if n := len(t.transcripts) - t.config.MaxTranscripts; n > 0 {
+		t.transcripts = append(t.transcripts[:0:0], t.transcripts[n:]...)
+	}
+}
+
+// setActive starts or stops the collection of audio.
+func (t *transcription) setActive(active bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.active = active
+	if !active {
+		for s, u := range t.utterances {
+			u.timer.Stop()
+			delete(t.utterances, s)
+		}
+	}
+}
+
+// isActive returns true if audio is being transcribed.
+func (t *transcription) isActive() bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.active
+}
+
+// list returns all stored transcripts.
+func (t *transcription) list() []*Transcript {
+	t.Lock()
+	defer t.Unlock()
+
+	transcripts := make([]*Transcript, len(t.transcripts))
+	copy(transcripts, t.transcripts)
+	return transcripts
+}
+
+// clear removes all stored transcripts.
+func (t *transcription) clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.transcripts = nil
+}
+
+// StartTranscription starts transcribing the audio in the Mumble channel.
+func (c *Client) StartTranscription() error {
+	if c.transcription == nil {
+		return fmt.Errorf("transcription is not configured")
+	}
+
+	c.transcription.setActive(true)
+	c.updateListening()
+	return nil
+}
+
+// StopTranscription stops transcribing the audio in the Mumble channel.
+func (c *Client) StopTranscription() error {
+	if c.transcription == nil {
+		return fmt.Errorf("transcription is not configured")
+	}
+
+	c.transcription.setActive(false)
+	c.updateListening()
+	return nil
+}
+
+// Transcribing returns true if the audio in the Mumble channel is being transcribed.
+func (c *Client) Transcribing() bool {
+	return c.transcription != nil && c.transcription.isActive()
+}
+
+// CheckTranscriptPermission returns an error if the sender of a context
+// is not allowed to use the transcript command, which also applies to reading transcripts.
+func (c *Client) CheckTranscriptPermission(ctx *Context) error {
+	return c.checkPermission(ctx, transcriptCommand)
+}
+
+// Transcripts returns all transcripts since the given time.
+func (c *Client) Transcripts(since time.Time) []*Transcript {
+	if c.transcription == nil {
+		return nil
+	}
+
+	transcripts := c.transcription.list()
+	i := sort.Search(len(transcripts), func(i int) bool {
+		return !transcripts[i].Time.Before(since)
+	})
+	return transcripts[i:]
+}
+
+// ClearTranscripts removes all stored transcripts.
+func (c *Client) ClearTranscripts() {
+	if c.transcription != nil {
+		c.transcription.clear()
+	}
+}
@@ -2,9 +2,12 @@ package bot
 
 import (
 	"fmt"
+	"html"
 	"io/ioutil"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode"
 )
 
 // keys returns the keys from a string indexed map.
@@ -17,9 +20,131 @@ func keys(m map[string]interface{}) []string {
 }
 
 // parseCommand splits a command string into the main command and its arguments.
-func parseCommand(s string) (cmd string, args []string) {
-	parts := strings.Split(s, " ")
-	return parts[0], parts[1:]
+// See splitWords for the supported syntax.
+func parseCommand(s string) (cmd string, args []string, err error) {
+	words, err := splitWords(s)
+	if err != nil || len(words) == 0 {
+		return "", nil, err
+	}
+	return words[0], words[1:], nil
+}
+
+// splitWords splits a string into words in a shell-like manner.
+// Words are separated by whitespace, and can be quoted using single or double quotes.
+// A backslash escapes the next character, except within single quotes.
+func splitWords(s string) (words []string, err error) {
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, fmt.Errorf("unterminated escape sequence")
+	case quote != 0:
+		return nil, fmt.Errorf("unterminated quote: %c", quote)
+	case inWord:
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// splitCommands splits a string into commands separated by semicolons.
+// Semicolons that are quoted or escaped as in splitWords are not treated as separators.
+// Empty commands are omitted.
+func splitCommands(s string) (commands []string, err error) {
+	var quote rune
+	escaped, start := false, 0
+
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			}
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';':
+			if cmd := strings.TrimSpace(s[start:i]); cmd != "" {
+				commands = append(commands, cmd)
+			}
+			start = i + 1
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote: %c", quote)
+	}
+	if cmd := strings.TrimSpace(s[start:]); cmd != "" {
+		commands = append(commands, cmd)
+	}
+
+	return commands, nil
+}
+
+// quoteWords joins words into a string that is split into the same words by splitWords.
+func quoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w != "" && !strings.ContainsAny(w, " \t\r\n'\"\\;") {
+			quoted[i] = w
+			continue
+		}
+		quoted[i] = "'" + strings.Replace(w, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// htmlTag matches HTML tags in a Mumble text message.
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// htmlBreak matches HTML line breaks and paragraphs in a Mumble text message.
+var htmlBreak = regexp.MustCompile(`(?i)<(br|/?p)\b[^>]*>`)
+
+// decodeMessage converts an HTML Mumble text message to plain text.
+func decodeMessage(s string) string {
+	s = htmlBreak.ReplaceAllString(s, " ")
+	s = htmlTag.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
 }
 
 // listFiles lists all files in a directory with a given extension.
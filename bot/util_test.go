@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in    string
+		words []string
+	}{
+		{``, nil},
+		{`   `, nil},
+		{`a b  c`, []string{"a", "b", "c"}},
+		{" a\tb\n", []string{"a", "b"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`'a "b"' c`, []string{`a "b"`, "c"}},
+		{`"a 'b'"`, []string{`a 'b'`}},
+		{`a\ b`, []string{"a b"}},
+		{`"a \"b\""`, []string{`a "b"`}},
+		{`'a\b'`, []string{`a\b`}},
+		{`a"b c"d`, []string{"ab cd"}},
+		{`""`, []string{""}},
+		{`'' x`, []string{"", "x"}},
+		{`\"`, []string{`"`}},
+	}
+
+	for _, tt := range tests {
+		words, err := splitWords(tt.in)
+		if err != nil {
+			t.Errorf("splitWords(%q) returned error: %s", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("splitWords(%q) = %q, expected %q", tt.in, words, tt.words)
+		}
+	}
+}
+
+func TestSplitWordsErrors(t *testing.T) {
+	for _, in := range []string{`"abc`, `'abc`, `abc\`, `a "b c`, `'it''s`} {
+		if words, err := splitWords(in); err == nil {
+			t.Errorf("splitWords(%q) = %q, expected an error", in, words)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	cmd, args, err := parseCommand(`play "hold music" loud`)
+	if err != nil {
+		t.Fatalf("parseCommand returned error: %s", err)
+	}
+	if cmd != "play" || !reflect.DeepEqual(args, []string{"hold music", "loud"}) {
+		t.Errorf("parseCommand = %q, %q, expected play, [hold music loud]", cmd, args)
+	}
+
+	if cmd, args, err := parseCommand(""); cmd != "" || args != nil || err != nil {
+		t.Errorf("parseCommand of an empty string = %q, %q, %v", cmd, args, err)
+	}
+}
+
+func TestQuoteWords(t *testing.T) {
+	tests := []struct {
+		words  []string
+		quoted string
+	}{
+		{[]string{"a", "b"}, `a b`},
+		{[]string{"a b"}, `'a b'`},
+		{[]string{""}, `''`},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{"a;b"}, `'a;b'`},
+	}
+
+	for _, tt := range tests {
+		if quoted := quoteWords(tt.words); quoted != tt.quoted {
+			t.Errorf("quoteWords(%q) = %q, expected %q", tt.words, quoted, tt.quoted)
+		}
+	}
+}
+
+func TestQuoteWordsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"a"},
+		{"a b", "c"},
+		{"", "x", ""},
+		{`it's`, `"quoted"`, `back\slash`},
+		{"semi;colon", "tab\tand\nnewline"},
+		{`'`, `"`, `\`, `'\''`},
+	}
+
+	for _, words := range tests {
+		split, err := splitWords(quoteWords(words))
+		if err != nil {
+			t.Errorf("splitWords(quoteWords(%q)) returned error: %s", words, err)
+			continue
+		}
+		if !reflect.DeepEqual(split, words) {
+			t.Errorf("splitWords(quoteWords(%q)) = %q", words, split)
+		}
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{`!help`, `!help`},
+		{`<b>!help</b>`, `!help`},
+		{`<p>!clip &lt;img&gt; &amp; more</p>`, `!clip <img> & more`},
+		{`!say a<br/>b`, `!say a b`},
+		{`<p>a</p><p>b</p>`, `a  b`},
+		{`  !roll 1d20  `, `!roll 1d20`},
+		{`&quot;quoted&quot;`, `"quoted"`},
+	}
+
+	for _, tt := range tests {
+		if out := decodeMessage(tt.in); out != tt.out {
+			t.Errorf("decodeMessage(%q) = %q, expected %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		in       string
+		commands []string
+	}{
+		{``, nil},
+		{` ; ;`, nil},
+		{`!play a`, []string{"!play a"}},
+		{`!play a; !stop`, []string{"!play a", "!stop"}},
+		{`!play a;;!stop;`, []string{"!play a", "!stop"}},
+		{`!say 'a; b'; !stop`, []string{"!say 'a; b'", "!stop"}},
+		{`!say "a; b"`, []string{`!say "a; b"`}},
+		{`!say a\; b`, []string{`!say a\; b`}},
+		{`!say "a \"; b"`, []string{`!say "a \"; b"`}},
+		{`!say 'a\'; b`, []string{`!say 'a\'`, "b"}},
+	}
+
+	for _, tt := range tests {
+		commands, err := splitCommands(tt.in)
+		if err != nil {
+			t.Errorf("splitCommands(%q) returned error: %s", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(commands, tt.commands) {
+			t.Errorf("splitCommands(%q) = %q, expected %q", tt.in, commands, tt.commands)
+		}
+	}
+
+	for _, in := range []string{`!say 'a; b`, `!say "a; b`, `!say "a\"; b`} {
+		if commands, err := splitCommands(in); err == nil {
+			t.Errorf("splitCommands(%q) = %q, expected an error", in, commands)
+		}
+	}
+}
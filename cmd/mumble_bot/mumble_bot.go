@@ -3,9 +3,11 @@ package main
 import (
 	"flag"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/silkeh/mumble_bot/api"
 	"github.com/silkeh/mumble_bot/bot"
@@ -15,16 +17,17 @@ func handleSignals(c *bot.Client, configFile string) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGHUP)
 
-	var err error
 	for {
 		s := <-signals
 		switch s {
 		case syscall.SIGHUP:
 			log.Printf("Reloading config file %q", configFile)
-			c.Config, err = bot.LoadConfig(configFile)
+			config, err := bot.LoadConfig(configFile)
 			if err != nil {
 				log.Printf("Error reloading config: %s", err)
+				continue
 			}
+			c.Reload(config)
 		}
 	}
 }
@@ -33,6 +36,7 @@ func main() {
 	var configFile string
 	flag.StringVar(&configFile, "config", "config.yaml", "Configuration file")
 	flag.Parse()
+	rand.Seed(time.Now().UnixNano())
 
 	config, err := bot.LoadConfig(configFile)
 	if err != nil {
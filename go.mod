@@ -7,6 +7,7 @@ require (
 	github.com/justinian/dice v1.0.1
 	github.com/matrix-org/gomatrix v0.0.0-20210324163249-be2af5ef2e16
 	github.com/pkg/errors v0.9.1 // indirect
+	go.starlark.net v0.0.0-20230302034142-4b1e35fe2254
 	gopkg.in/hraban/opus.v2 v2.0.0-20210415224706-ab1467d63813
 	gopkg.in/tucnak/telebot.v2 v2.3.5
 	gopkg.in/yaml.v2 v2.4.0
@@ -7,31 +7,65 @@ import (
 	"time"
 )
 
+// Message is a text message received in the configured room
+type Message struct {
+	Sender string
+	Text   string
+}
+
 // Client is a simplified Matrix client that can send to a single room
 type Client struct {
 	*matrix.Client
-	Syncer *matrix.DefaultSyncer
-	roomID string
+	Syncer   *matrix.DefaultSyncer
+	Messages chan *Message
+	roomID   string
 }
 
 // NewClient returns a configured Matrix Client
 func NewClient(homeserverURL, userID, accessToken, roomID string) (c *Client, err error) {
-	c = &Client{roomID: roomID}
+	c = &Client{roomID: roomID, Messages: make(chan *Message)}
 	c.Client, err = matrix.NewClient(homeserverURL, userID, accessToken)
 	if err != nil {
 		return
 	}
 	c.Syncer = c.Client.Syncer.(*matrix.DefaultSyncer)
+	c.Syncer.OnEventType("m.room.message", c.messageHandler(time.Now()))
 	_, err = c.JoinRoom(roomID, "", nil)
 
 	return
 }
 
+// messageHandler returns a handler that passes on the text messages
+// sent by others to the configured room after the given time
+func (c *Client) messageHandler(since time.Time) matrix.OnEventListener {
+	return func(e *matrix.Event) {
+		if e.RoomID != c.roomID || e.Sender == c.UserID || e.Timestamp < since.UnixNano()/int64(time.Millisecond) {
+			return
+		}
+		if t, _ := e.MessageType(); t != "m.text" {
+			return
+		}
+		if body, ok := e.Body(); ok {
+			c.Messages <- &Message{Sender: e.Sender, Text: body}
+		}
+	}
+}
+
 // SendSticker sends a sticker to the configured room
 func (c *Client) SendSticker(s *Sticker) (resp *matrix.RespSendEvent, err error) {
 	return c.SendMessageEvent(c.roomID, "m.sticker", s)
 }
 
+// SendText sends a plain text message to the configured room
+func (c *Client) SendText(text string) (resp *matrix.RespSendEvent, err error) {
+	return c.Client.SendText(c.roomID, text)
+}
+
+// SendHTML sends a message with a plain text body and an HTML formatted body to the configured room
+func (c *Client) SendHTML(text, html string) (resp *matrix.RespSendEvent, err error) {
+	return c.Client.SendFormattedText(c.roomID, text, html)
+}
+
 // Sync runs a blocking sync-thread
 func (c *Client) Sync() {
 	for {
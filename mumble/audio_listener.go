@@ -1,43 +1,211 @@
 package mumble
 
 import (
-	"log"
 	"sync"
 	"time"
 
 	"layeh.com/gumble/gumble"
 )
 
-// AudioListener implements a simple listener that can record audio.
+// mixedBuffer is the number of mixed audio frames buffered for each subscriber.
+const mixedBuffer = 50
+
+// AudioHandler processes decoded audio received from a single user.
+type AudioHandler interface {
+	// HandleAudio is called with every decoded 16-bit 48k PCM audio frame
+	// received from a user.
+	HandleAudio(user *gumble.User, pcm []int16)
+}
+
+// AudioListener implements a listener that decodes the audio streams of all users.
+// The audio of every user is passed through a jitter buffer, and is played out
+// in frames of gumble.AudioDefaultFrameSize samples to the registered handlers.
+// The audio of all users is mixed into a single stream for subscribers and recording.
+//
+// Packets that arrive out of order are not reordered, as gumble does not expose
+// the sequence numbers of audio packets. They are played in the order they arrive.
 type AudioListener struct {
 	sync.Mutex
-	buffer []gumble.AudioPacket
+	once        sync.Once
+	streams     map[uint32]*jitterBuffer
+	handlers    []AudioHandler
+	subscribers []chan []int16
+	buffer      []int16
 }
 
 // OnAudioStream handles AudioStreamEvents.
 func (al *AudioListener) OnAudioStream(e *gumble.AudioStreamEvent) {
+	al.start()
+
 	go func() {
 		for p := range e.C {
-			al.Lock()
-			if al.buffer != nil {
-				log.Printf("Storing %v/%v", len(al.buffer), cap(al.buffer))
-				al.buffer = append(al.buffer, *p)
-			}
-			al.Unlock()
+			al.stream(p.Sender).Push(p.AudioBuffer)
 		}
 	}()
 }
 
-// setBuffer initializes the buffer to a given size in packets.
+// start starts the playout of received audio.
+func (al *AudioListener) start() {
+	al.once.Do(func() {
+		al.Lock()
+		al.streams = make(map[uint32]*jitterBuffer)
+		al.Unlock()
+		go al.playout()
+	})
+}
+
+// stream returns the jitter buffer for a user.
+func (al *AudioListener) stream(user *gumble.User) *jitterBuffer {
+	al.Lock()
+	defer al.Unlock()
+
+	b, ok := al.streams[user.Session]
+	if !ok || b.user != user {
+		b = newJitterBuffer(user)
+		al.streams[user.Session] = b
+	}
+	return b
+}
+
+// playout plays out a frame of the buffered audio of all users every audio interval.
+func (al *AudioListener) playout() {
+	ticker := time.NewTicker(gumble.AudioDefaultInterval)
+	defer ticker.Stop()
+
+	mix := make([]int32, gumble.AudioDefaultFrameSize)
+	for range ticker.C {
+		al.Lock()
+		streams := make([]*jitterBuffer, 0, len(al.streams))
+		for s, b := range al.streams {
+			if b.Expired() {
+				delete(al.streams, s)
+				continue
+			}
+			streams = append(streams, b)
+		}
+		handlers := al.handlers
+		al.Unlock()
+
+		for i := range mix {
+			mix[i] = 0
+		}
+
+		for _, b := range streams {
+			frame := b.Pop(len(mix))
+			if frame == nil {
+				continue
+			}
+
+			for i, s := range frame {
+				mix[i] += int32(s)
+			}
+			for _, h := range handlers {
+				h.HandleAudio(b.user, frame)
+			}
+		}
+
+		al.publish(mixFrame(mix))
+	}
+}
+
+// mixFrame converts a frame of summed samples to 16-bit samples, clipping where needed.
+func mixFrame(mix []int32) []int16 {
+	frame := make([]int16, len(mix))
+	for i, s := range mix {
+		switch {
+		case s > 32767:
+			frame[i] = 32767
+		case s < -32768:
+			frame[i] = -32768
+		default:
+			frame[i] = int16(s)
+		}
+	}
+	return frame
+}
+
+// publish sends a mixed frame to all subscribers and the recording buffer.
+// Frames are dropped for subscribers that are not keeping up.
+func (al *AudioListener) publish(frame []int16) {
+	al.Lock()
+	defer al.Unlock()
+
+	if al.buffer != nil && len(al.buffer) < cap(al.buffer) {
+		n := cap(al.buffer) - len(al.buffer)
+		if n > len(frame) {
+			n = len(frame)
+		}
+		al.buffer = append(al.buffer, frame[:n]...)
+	}
+
+	for _, ch := range al.subscribers {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// AddHandler registers a handler for decoded audio of individual users.
+func (al *AudioListener) AddHandler(h AudioHandler) {
+	al.Lock()
+	defer al.Unlock()
+
+	al.handlers = append(al.handlers[:len(al.handlers):len(al.handlers)], h)
+}
+
+// RemoveHandler removes a previously added handler.
+func (al *AudioListener) RemoveHandler(h AudioHandler) {
+	al.Lock()
+	defer al.Unlock()
+
+	handlers := make([]AudioHandler, 0, len(al.handlers))
+	for _, o := range al.handlers {
+		if o != h {
+			handlers = append(handlers, o)
+		}
+	}
+	al.handlers = handlers
+}
+
+// Subscribe returns a channel that receives the mixed audio of all users
+// in frames of gumble.AudioDefaultFrameSize samples.
+// Silence is sent when nobody is speaking.
+func (al *AudioListener) Subscribe() <-chan []int16 {
+	al.start()
+
+	al.Lock()
+	defer al.Unlock()
+
+	ch := make(chan []int16, mixedBuffer)
+	al.subscribers = append(al.subscribers, ch)
+	return ch
+}
+
+// Unsubscribe stops sending audio to a channel returned by Subscribe, and closes it.
+func (al *AudioListener) Unsubscribe(ch <-chan []int16) {
+	al.Lock()
+	defer al.Unlock()
+
+	for i, o := range al.subscribers {
+		if o == ch {
+			al.subscribers = append(al.subscribers[:i], al.subscribers[i+1:]...)
+			close(o)
+			return
+		}
+	}
+}
+
+// setBuffer initializes the buffer to a given size in samples.
 func (al *AudioListener) setBuffer(size int) {
 	al.Lock()
 	defer al.Unlock()
 
-	al.buffer = make([]gumble.AudioPacket, 0, size)
+	al.buffer = make([]int16, 0, size)
 }
 
 // getBuffer returns and clears the current audio buffer.
-func (al *AudioListener) getBuffer() []gumble.AudioPacket {
+func (al *AudioListener) getBuffer() []int16 {
 	al.Lock()
 	defer al.Unlock()
 
@@ -46,9 +214,11 @@ func (al *AudioListener) getBuffer() []gumble.AudioPacket {
 	return buf
 }
 
-// Record records for a certain time and returns the recorded audio samples.
-func (al *AudioListener) Record(duration time.Duration) []gumble.AudioPacket {
-	al.setBuffer(int(duration * gumble.AudioSampleRate / time.Second))
+// Record records the mixed audio of all users for a certain time
+// and returns the recorded 16-bit 48k PCM audio samples.
+func (al *AudioListener) Record(duration time.Duration) []int16 {
+	al.start()
+	al.setBuffer(samples(duration))
 	time.Sleep(duration)
 	return al.getBuffer()
 }
@@ -23,6 +23,7 @@ type Client struct {
 	stopAudio     bool
 	selfMuted     bool
 	selfDeafened  bool
+	groups        map[string]map[uint32]bool
 }
 
 // NewClient initialises and returns a Mumble Client.
@@ -40,6 +41,7 @@ func NewClient(server, user string) (c *Client, err error) {
 	config.Attach(gumbleutil.Listener{
 		UserChange:  c.changeHandler,
 		TextMessage: c.textMessageHandler,
+		ACL:         c.aclHandler,
 	})
 
 	// Create connection
@@ -52,27 +54,18 @@ func NewClient(server, user string) (c *Client, err error) {
 	return
 }
 
-// changeHandler handles room membership changes.
+// changeHandler handles room membership changes and users moving between channels.
 func (c *Client) changeHandler(e *gumble.UserChangeEvent) {
-	if e.Type.Has(gumble.UserChangeConnected) || e.Type.Has(gumble.UserChangeDisconnected) {
+	if e.Type.Has(gumble.UserChangeConnected) || e.Type.Has(gumble.UserChangeDisconnected) ||
+		e.Type.Has(gumble.UserChangeChannel) {
 		c.UserChanges <- e
 	}
 }
 
-// textMessageHandler handles text messages, and passes commands on.
+// textMessageHandler passes text messages on.
+// Messages are HTML, so the bot decodes them before looking for commands.
 func (c *Client) textMessageHandler(e *gumble.TextMessageEvent) {
-	if strings.HasPrefix(e.TextMessage.Message, "!") {
-		c.Messages <- &e.TextMessage
-	}
-}
-
-// SendTextResponse sends a simple text response to the given message.
-func (c *Client) SendTextResponse(e *gumble.TextMessage, msg string) {
-	c.Send(&gumble.TextMessage{
-		Sender:   c.Self,
-		Channels: e.Channels,
-		Message:  msg,
-	})
+	c.Messages <- &e.TextMessage
 }
 
 // SendAudio sends the given 48 kHz 16-bit PCM audio to the main audio channel.
@@ -196,3 +189,27 @@ func (c *Client) SetSelfDeafened(deafened bool) {
 	c.selfDeafened = deafened
 	c.Self.SetSelfDeafened(deafened)
 }
+
+// FindChannel returns the channel with the given name, or nil.
+// Names containing a slash are looked up as a path from the root channel.
+func (c *Client) FindChannel(name string) *gumble.Channel {
+	if strings.Contains(name, "/") {
+		return c.Channels.Find(strings.Split(strings.Trim(name, "/"), "/")...)
+	}
+	for _, ch := range c.Channels {
+		if strings.EqualFold(ch.Name, name) {
+			return ch
+		}
+	}
+	return nil
+}
+
+// FindUser returns the connected user with the given name, or nil.
+func (c *Client) FindUser(name string) *gumble.User {
+	for _, u := range c.Users {
+		if strings.EqualFold(u.Name, name) {
+			return u
+		}
+	}
+	return nil
+}
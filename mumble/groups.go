@@ -0,0 +1,78 @@
+package mumble
+
+import (
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// groupRefreshInterval is the interval at which the ACL groups are refreshed.
+const groupRefreshInterval = time.Minute
+
+// aclHandler stores the members of the groups in the ACL of the root channel.
+func (c *Client) aclHandler(e *gumble.ACLEvent) {
+	if e.ACL.Channel == nil || e.ACL.Channel.ID != 0 {
+		return
+	}
+
+	groups := make(map[string]map[uint32]bool, len(e.ACL.Groups))
+	for _, g := range e.ACL.Groups {
+		members := make(map[uint32]bool, len(g.UsersAdd)+len(g.UsersInherited))
+		for id := range g.UsersInherited {
+			members[id] = true
+		}
+		for id := range g.UsersAdd {
+			members[id] = true
+		}
+		for id := range g.UsersRemove {
+			delete(members, id)
+		}
+		groups[g.Name] = members
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.groups = groups
+}
+
+// RefreshGroups periodically requests the ACL of the root channel.
+// It should only be started when group membership is used, as
+// the bot needs permission to edit the ACL of the root channel for this to succeed.
+func (c *Client) RefreshGroups() {
+	ticker := time.NewTicker(groupRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		c.requestGroups()
+		<-ticker.C
+	}
+}
+
+// requestGroups requests the ACL of the root channel.
+// The channel is looked up while holding the lock of the gumble client.
+func (c *Client) requestGroups() {
+	c.Do(func() {
+		if root := c.Channels[0]; root != nil {
+			root.RequestACL()
+		}
+	})
+}
+
+// InGroup returns true if the user is a member of the given group
+// in the ACL of the root channel.
+func (c *Client) InGroup(user *gumble.User, group string) bool {
+	switch group {
+	case gumble.ACLGroupEveryone:
+		return true
+	case gumble.ACLGroupAuthenticated:
+		return user.IsRegistered()
+	}
+
+	if !user.IsRegistered() {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return c.groups[group][user.UserID]
+}
@@ -0,0 +1,107 @@
+package mumble
+
+import (
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+const (
+	// jitterDelay is the amount of audio that is buffered before playout of a stream starts.
+	jitterDelay = 40 * time.Millisecond
+
+	// jitterMaxDelay is the maximum amount of buffered audio.
+	// Older audio is dropped when more audio is buffered.
+	jitterMaxDelay = 200 * time.Millisecond
+
+	// jitterTimeout is the time after the last received packet after which a stream is considered ended.
+	jitterTimeout = 100 * time.Millisecond
+
+	// jitterExpiry is the time after the last received packet after which a buffer is discarded.
+	jitterExpiry = time.Minute
+)
+
+// samples returns the number of samples in a duration of audio.
+func samples(d time.Duration) int {
+	return int(d * gumble.AudioSampleRate / time.Second)
+}
+
+// jitterBuffer is a small buffer for the decoded audio of a single user.
+// It smooths out packets arriving irregularly by delaying playout,
+// conceals missing packets with silence and drops audio that arrives too late.
+//
+// Gumble decodes packets in the order they arrive and does not expose their
+// sequence numbers, so packets that arrive out of order can not be reordered.
+type jitterBuffer struct {
+	sync.Mutex
+	user     *gumble.User
+	buffer   []int16
+	playing  bool
+	received time.Time
+
+	// Lost contains the number of samples that were concealed with silence.
+	Lost int
+
+	// Late contains the number of samples that were dropped.
+	Late int
+}
+
+// newJitterBuffer returns a jitterBuffer for a user.
+func newJitterBuffer(user *gumble.User) *jitterBuffer {
+	return &jitterBuffer{
+		user:   user,
+		buffer: make([]int16, 0, samples(jitterMaxDelay)),
+	}
+}
+
+// Push adds decoded audio to the buffer.
+// Audio is appended in the order it is received, see the limitation on reordering above.
+func (b *jitterBuffer) Push(pcm []int16) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.received = time.Now()
+	b.buffer = append(b.buffer, pcm...)
+	if n := len(b.buffer) - samples(jitterMaxDelay); n > 0 {
+		b.Late += n
+		b.buffer = append(b.buffer[:0], b.buffer[n:]...)
+	}
+}
+
+// Pop returns a frame of audio of the given size, or nil if the stream is not playing.
+func (b *jitterBuffer) Pop(size int) []int16 {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.playing {
+		if len(b.buffer) < samples(jitterDelay) {
+			return nil
+		}
+		b.playing = true
+	}
+
+	if len(b.buffer) < size && time.Since(b.received) > jitterTimeout {
+		b.playing = false
+		if len(b.buffer) == 0 {
+			return nil
+		}
+	}
+
+	frame := make([]int16, size)
+	n := copy(frame, b.buffer)
+	b.buffer = append(b.buffer[:0], b.buffer[n:]...)
+	if b.playing {
+		b.Lost += size - n
+	}
+
+	return frame
+}
+
+// Expired returns true if the stream has ended and can be discarded.
+func (b *jitterBuffer) Expired() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return !b.playing && len(b.buffer) == 0 && time.Since(b.received) > jitterExpiry
+}
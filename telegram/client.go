@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"log"
+	"strconv"
 	"time"
 
 	tb "gopkg.in/tucnak/telebot.v2"
@@ -15,28 +16,69 @@ func (t Target) Recipient() string {
 	return string(t)
 }
 
+// Message is a text message received from the recipient (Target).
+// The sender is the numeric ID of the user, as usernames can be changed or reused.
+type Message struct {
+	Sender string
+	Name   string
+	Text   string
+}
+
 // Client is a simplified Telegram client with a single recipient (Target).
 type Client struct {
 	*tb.Bot
-	Target tb.Recipient
+	Target   tb.Recipient
+	Messages chan *Message
 }
 
 // NewClient returns a configured Telegram client.
 func NewClient(token, target string) (c *Client, err error) {
-	c = &Client{Target: Target(target)}
+	c = &Client{Target: Target(target), Messages: make(chan *Message)}
 	c.Bot, err = tb.NewBot(tb.Settings{
 		Token:  token,
 		Poller: &tb.LongPoller{Timeout: 10 * time.Second},
 	})
+	if err != nil {
+		return
+	}
 
 	c.Handle(tb.OnSticker, func(m *tb.Message) {
 		log.Printf("Received a sticker: %#v", m.Sticker)
 	})
+	c.Handle(tb.OnText, c.textHandler)
 
 	return
 }
 
+// textHandler passes on text messages sent by users in the target chat.
+func (c *Client) textHandler(m *tb.Message) {
+	if m.Sender == nil || m.Chat == nil {
+		return
+	}
+	target := c.Target.Recipient()
+	if target != strconv.FormatInt(m.Chat.ID, 10) && target != "@"+m.Chat.Username {
+		return
+	}
+
+	id := strconv.Itoa(m.Sender.ID)
+	name := m.Sender.Username
+	if name == "" {
+		name = id
+	}
+	c.Messages <- &Message{Sender: id, Name: name, Text: m.Text}
+}
+
 // SendSticker sends a sticker to the configured recipient.
 func (c *Client) SendSticker(sticker *tb.Sticker) (*tb.Message, error) {
 	return sticker.Send(c.Bot, c.Target, nil)
 }
+
+// SendText sends a text message to the configured recipient.
+func (c *Client) SendText(text string) (*tb.Message, error) {
+	return c.Send(c.Target, text)
+}
+
+// SendHTML sends a message formatted with Telegram HTML to the configured recipient.
+func (c *Client) SendHTML(html string) (*tb.Message, error) {
+	return c.Send(c.Target, html, tb.ModeHTML)
+}
@@ -0,0 +1,76 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP is a Transcriber that posts audio to an HTTP endpoint,
+// such as a self-hosted Whisper server.
+// The audio is sent as a 16 kHz WAV file in the `file` field of a multipart form,
+// and a JSON response containing a `text` field is expected.
+type HTTP struct {
+	URL    string
+	Fields map[string]string
+	Client *http.Client
+}
+
+// NewHTTP returns a HTTP transcriber for the given URL and additional form fields.
+func NewHTTP(url string, fields map[string]string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		URL:    url,
+		Fields: fields,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// response represents the response of the transcription endpoint.
+type response struct {
+	Text string `json:"text"`
+}
+
+// Transcribe converts 16-bit 48k PCM audio to text.
+func (t *HTTP) Transcribe(pcm []int16) (string, error) {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	for k, v := range t.Fields {
+		if err := form.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	if _, err = file.Write(encodeWAV(resample(pcm), SampleRate)); err != nil {
+		return "", err
+	}
+	if err = form.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := t.Client.Post(t.URL, form.FormDataContentType(), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("transcription failed: %s: %s", resp.Status, msg)
+	}
+
+	var r response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("decoding transcription: %w", err)
+	}
+
+	return strings.TrimSpace(r.Text), nil
+}
@@ -0,0 +1,57 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// SampleRate is the sample rate of the audio sent to a transcription backend.
+const SampleRate = 16000
+
+// inputSampleRate is the sample rate of the audio that is transcribed.
+const inputSampleRate = 48000
+
+// Transcriber converts speech to text.
+type Transcriber interface {
+	// Transcribe converts 16-bit 48k PCM audio to text.
+	Transcribe(pcm []int16) (string, error)
+}
+
+// resample converts 48k audio to the sample rate used for transcription.
+func resample(pcm []int16) []int16 {
+	factor := inputSampleRate / SampleRate
+	out := make([]int16, len(pcm)/factor)
+	for i := range out {
+		var sum int
+		for _, s := range pcm[i*factor : i*factor+factor] {
+			sum += int(s)
+		}
+		out[i] = int16(sum / factor)
+	}
+	return out
+}
+
+// encodeWAV encodes 16-bit mono PCM audio as a WAV file.
+func encodeWAV(pcm []int16, rate int) []byte {
+	buf := new(bytes.Buffer)
+	size := uint32(2 * len(pcm))
+
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, 36+size)
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))     // chunk size
+	binary.Write(buf, binary.LittleEndian, uint16(1))      // PCM
+	binary.Write(buf, binary.LittleEndian, uint16(1))      // channels
+	binary.Write(buf, binary.LittleEndian, uint32(rate))   // sample rate
+	binary.Write(buf, binary.LittleEndian, uint32(2*rate)) // byte rate
+	binary.Write(buf, binary.LittleEndian, uint16(2))      // block align
+	binary.Write(buf, binary.LittleEndian, uint16(16))     // bits per sample
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, size)
+	binary.Write(buf, binary.LittleEndian, pcm)
+
+	return buf.Bytes()
+}